@@ -0,0 +1,28 @@
+package assay
+
+// GetHarmonicPartials returns the frequency ratios and relative amplitudes
+// of the peaks compared to the first peak, which is treated as the
+// fundamental. The result can be used directly as partials for additive
+// synthesis.
+func GetHarmonicPartials(peaks []int, frequencies, magnitudes []float64) ([]float64, []float64) {
+
+	ratios := make([]float64, 0, len(peaks))
+	amplitudes := make([]float64, 0, len(peaks))
+
+	if len(peaks) == 0 {
+		return ratios, amplitudes
+	}
+
+	baseFreq := frequencies[peaks[0]]
+	baseMag := magnitudes[peaks[0]]
+	if baseFreq == 0 || baseMag == 0 {
+		return ratios, amplitudes
+	}
+
+	for _, peakIdx := range peaks {
+		ratios = append(ratios, frequencies[peakIdx]/baseFreq)
+		amplitudes = append(amplitudes, magnitudes[peakIdx]/baseMag)
+	}
+
+	return ratios, amplitudes
+}
@@ -0,0 +1,43 @@
+package assay
+
+import "github.com/rawbits2010/LibBitDauer/package/synth"
+
+type Pitch struct {
+	Frequency float64 // in Hz
+	MIDINote  uint8   // the nearest note
+	Name      string  // the name of the nearest note, e.g. "A4"
+	Cents     float64 // the difference from the nearest note
+}
+
+// GetPitch returns the nearest note of the frequency in Hz in the default
+// tuning, with the difference in cents. The name uses sharps. Returns false
+// if the frequency is not positive.
+func GetPitch(frequency float64) (Pitch, bool) {
+
+	midiNote, cents, ok := synth.GetNearestNote(frequency)
+	if !ok {
+		return Pitch{}, false
+	}
+
+	return Pitch{
+		Frequency: frequency,
+		MIDINote:  midiNote,
+		Name:      synth.FormatNoteName(midiNote, synth.SharpNaming),
+		Cents:     cents,
+	}, true
+}
+
+// GetDominantPitch returns the pitch of the frequency with the highest
+// magnitude. Returns false if there is none.
+func GetDominantPitch(frequencies, magnitudes []float64) (Pitch, bool) {
+	if len(frequencies) == 0 || len(frequencies) != len(magnitudes) {
+		return Pitch{}, false
+	}
+	return GetPitch(frequencies[FindDominantFrequencyIdx(magnitudes)])
+}
+
+// Transpose returns the pitch moved by semitones in 12 note equal
+// temperament, with the nearest note recalculated.
+func (pitch Pitch) Transpose(semitones float64) (Pitch, bool) {
+	return GetPitch(synth.TransposeFrequency(pitch.Frequency, semitones))
+}
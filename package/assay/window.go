@@ -1,8 +1,7 @@
 package assay
 
 import (
-	"math"
-
+	"github.com/rawbits2010/LibBitDauer/package/synth/generator"
 	"gonum.org/v1/gonum/dsp/window"
 )
 
@@ -31,26 +30,14 @@ Potential Drawbacks:
 // Moderate side-lobe suppression with good frequency resolution. Suitable for
 // general-purpose use when no specific windowing requirements exist.
 func GetHannWindow(size int) []float64 {
-
-	window := make([]float64, size)
-	for i := 0; i < size; i++ {
-		window[i] = 0.5 * (1 - math.Cos(2*math.Pi*float64(i)/float64(size-1)))
-	}
-
-	return window
+	return generator.GetHannWindow(size)
 }
 
 // Better side-lobe suppression than Hann, but slightly worse frequency
 // resolution. Useful when minimizing spectral leakage is more important
 // than precise frequency localization.
 func GetHammingWindow(size int) []float64 {
-
-	window := make([]float64, size)
-	for i := 0; i < size; i++ {
-		window[i] = 0.54 - 0.46*math.Cos(2*math.Pi*float64(i)/float64(size-1))
-	}
-
-	return window
+	return generator.GetHammingWindow(size)
 }
 
 // Strongest side-lobe suppression of the three, but the main lobe is wider,
@@ -58,13 +45,7 @@ func GetHammingWindow(size int) []float64 {
 // Ideal for applications where minimizing side-lobes is critical, even at the
 // expense of less sharp frequency peaks.
 func GetBlackmanWindow(size int) []float64 {
-
-	window := make([]float64, size)
-	for i := 0; i < size; i++ {
-		window[i] = 0.42 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(size-1)) + 0.08*math.Cos(4*math.Pi*float64(i)/float64(size-1))
-	}
-
-	return window
+	return generator.GetBlackmanWindow(size)
 }
 
 func ApplyHannWindow(samples []float64) []float64 {
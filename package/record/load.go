@@ -0,0 +1,52 @@
+package record
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/go-audio/wav"
+)
+
+// ReadFromWav reads the given WAV file and returns the samples as [-1-1]
+// values with the sample rate of the file. Multi-channel files are mixed
+// down to 1 channel.
+func ReadFromWav(fileName string) ([]float64, uint32, error) {
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, 0, fmt.Errorf("couldn't open file: '%s': %w", fileName, err)
+	}
+	defer f.Close()
+
+	decoder := wav.NewDecoder(f)
+	if !decoder.IsValidFile() {
+		return nil, 0, fmt.Errorf("invalid WAV file: '%s'", fileName)
+	}
+
+	audioBuf, err := decoder.FullPCMBuffer()
+	if err != nil {
+		return nil, 0, fmt.Errorf("couldn't read samples from file '%s': %w", fileName, err)
+	}
+
+	channels := audioBuf.Format.NumChannels
+	if channels < 1 {
+		channels = 1
+	}
+
+	// 8 bit values are unsigned, the rest are signed
+	var offset float64
+	fullScale := float64(int64(1) << (audioBuf.SourceBitDepth - 1))
+	if audioBuf.SourceBitDepth == 8 {
+		offset = 128
+	}
+
+	samples := make([]float64, len(audioBuf.Data)/channels)
+	for sampleIdx := range samples {
+		for ch := 0; ch < channels; ch++ {
+			samples[sampleIdx] += (float64(audioBuf.Data[sampleIdx*channels+ch]) - offset) / fullScale
+		}
+		samples[sampleIdx] /= float64(channels)
+	}
+
+	return samples, uint32(audioBuf.Format.SampleRate), nil
+}
@@ -0,0 +1,295 @@
+package synth
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/rawbits2010/LibBitDauer/package/synth/buffer"
+)
+
+type ArpMode int
+
+const (
+	ArpUp       ArpMode = iota // from the lowest note to the highest
+	ArpDown                    // from the highest note to the lowest
+	ArpUpDown                  // up then down, without repeating the ends
+	ArpRandom                  // a random note every step
+	ArpAsPlayed                // in the order the notes were added
+)
+
+type Arpeggiator struct {
+	Octaves    int     // the number of octaves the notes are repeated in, at least 1
+	GateLength float64 // the length of the notes relative to the step [0-1]
+	Velocity   float64 // [0-1]
+
+	voices *VoiceManager
+	mode   ArpMode
+
+	notes          []uint8 // in the order they were added
+	pattern        []uint8
+	patternOctaves int
+
+	stepLength float64 // in samples
+	currSample uint64
+	nextStep   float64 // the sample of the next step
+	gateEnd    float64 // the sample of the note-off
+	stepIdx    int
+
+	playing     bool
+	playingNote uint8
+
+	rndSeed int64
+	rndFunc *rand.Rand
+}
+
+// NewArpeggiator creates an arpeggiator that implements the Generator
+// interface. It plays the notes one after the other on the voice manager at
+// the rate set with SetRate, default is 16th notes at 120 BPM, and returns
+// the sound of the voices.
+func NewArpeggiator(voices *VoiceManager) *Arpeggiator {
+
+	arpTmp := &Arpeggiator{
+		Octaves:    1,
+		GateLength: 0.5,
+		Velocity:   1,
+		voices:     voices,
+		mode:       ArpUp,
+	}
+	arpTmp.SetSeed(0)
+	arpTmp.SetRate(120, buffer.SixteenthNote)
+
+	return arpTmp
+}
+
+// GetSampleRate returns the sample rate of the voices.
+func (arp Arpeggiator) GetSampleRate() uint {
+	return arp.voices.GetSampleRate()
+}
+
+// GetVoiceManager returns the voice manager the notes are played on.
+func (arp Arpeggiator) GetVoiceManager() *VoiceManager {
+	return arp.voices
+}
+
+// SetMode sets the order the notes are played in. The pattern continues
+// from the current step.
+func (arp *Arpeggiator) SetMode(mode ArpMode) error {
+	if mode < ArpUp || mode > ArpAsPlayed {
+		return fmt.Errorf("invalid arpeggiator mode: %d", mode)
+	}
+	arp.mode = mode
+	arp.buildPattern()
+	return nil
+}
+
+// GetMode returns the order the notes are played in.
+func (arp Arpeggiator) GetMode() ArpMode {
+	return arp.mode
+}
+
+// SetRate sets the length of the steps to a note division at a tempo in
+// BPM. It takes effect from the next step.
+func (arp *Arpeggiator) SetRate(bpm float64, div buffer.NoteDivision) error {
+
+	length, err := buffer.CalcDivisionSampleLength(arp.GetSampleRate(), bpm, div)
+	if err != nil {
+		return err
+	}
+	if length < 1 {
+		return fmt.Errorf("invalid step length: %f samples", length)
+	}
+
+	arp.stepLength = length
+	return nil
+}
+
+// SetStepLength sets the length of the steps in ms. It takes effect from
+// the next step.
+func (arp *Arpeggiator) SetStepLength(durationMS uint) error {
+
+	length := buffer.CalcSampleLength(arp.GetSampleRate(), durationMS)
+	if length < 1 {
+		return fmt.Errorf("invalid step length: %d ms", durationMS)
+	}
+
+	arp.stepLength = float64(length)
+	return nil
+}
+
+// SetSeed sets the seed for the random order for consistency.
+func (arp *Arpeggiator) SetSeed(seed int64) {
+	arp.rndSeed = seed
+	arp.rndFunc = rand.New(rand.NewSource(seed))
+}
+
+// SetNotes replaces the notes with the MIDI notes, e.g. a chord from
+// GetChordNotes. The order is kept for ArpAsPlayed.
+func (arp *Arpeggiator) SetNotes(notes []uint8) {
+	arp.notes = arp.notes[:0]
+	for _, midiNote := range notes {
+		arp.addNote(midiNote)
+	}
+	arp.buildPattern()
+}
+
+// AddNote adds a MIDI note to the notes, like holding down a key.
+func (arp *Arpeggiator) AddNote(midiNote uint8) {
+	arp.addNote(midiNote)
+	arp.buildPattern()
+}
+
+// RemoveNote removes a MIDI note from the notes, like releasing a key.
+func (arp *Arpeggiator) RemoveNote(midiNote uint8) {
+	for noteIdx, note := range arp.notes {
+		if note == midiNote {
+			arp.notes = append(arp.notes[:noteIdx], arp.notes[noteIdx+1:]...)
+			break
+		}
+	}
+	arp.buildPattern()
+}
+
+// ClearNotes removes all the notes and releases the playing one.
+func (arp *Arpeggiator) ClearNotes() {
+	arp.notes = arp.notes[:0]
+	arp.buildPattern()
+	arp.releaseNote()
+}
+
+// GetNotes returns the notes in the order they were added.
+func (arp Arpeggiator) GetNotes() []uint8 {
+	notes := make([]uint8, len(arp.notes))
+	copy(notes, arp.notes)
+	return notes
+}
+
+// addNote adds the note if it's not in the notes yet.
+func (arp *Arpeggiator) addNote(midiNote uint8) {
+	for _, note := range arp.notes {
+		if note == midiNote {
+			return
+		}
+	}
+	arp.notes = append(arp.notes, midiNote)
+}
+
+// buildPattern builds the order of the notes for the mode and the octaves.
+// The notes above the MIDI range are left out.
+func (arp *Arpeggiator) buildPattern() {
+
+	octaves := arp.Octaves
+	if octaves < 1 {
+		octaves = 1
+	}
+	arp.patternOctaves = arp.Octaves
+
+	base := make([]uint8, len(arp.notes))
+	copy(base, arp.notes)
+	if arp.mode != ArpAsPlayed {
+		sort.Slice(base, func(i, j int) bool { return base[i] < base[j] })
+	}
+
+	notes := make([]uint8, 0, len(base)*octaves)
+	for octave := 0; octave < octaves; octave++ {
+		for _, midiNote := range base {
+			transposed, err := TransposeMIDINote(midiNote, octave*12)
+			if err != nil {
+				continue
+			}
+			notes = append(notes, transposed)
+		}
+	}
+
+	switch arp.mode {
+	case ArpDown:
+		for i, j := 0, len(notes)-1; i < j; i, j = i+1, j-1 {
+			notes[i], notes[j] = notes[j], notes[i]
+		}
+
+	case ArpUpDown:
+		for noteIdx := len(notes) - 2; noteIdx > 0; noteIdx-- {
+			notes = append(notes, notes[noteIdx])
+		}
+	}
+
+	arp.pattern = notes
+}
+
+// releaseNote stops the playing note.
+func (arp *Arpeggiator) releaseNote() {
+	if arp.playing {
+		arp.voices.NoteOffMIDI(arp.playingNote)
+		arp.playing = false
+	}
+}
+
+// nextNote returns the note of the next step, or false if there are none.
+func (arp *Arpeggiator) nextNote() (uint8, bool) {
+
+	if arp.Octaves != arp.patternOctaves {
+		arp.buildPattern()
+	}
+
+	if len(arp.pattern) == 0 {
+		return 0, false
+	}
+
+	if arp.mode == ArpRandom {
+		return arp.pattern[arp.rndFunc.Intn(len(arp.pattern))], true
+	}
+
+	midiNote := arp.pattern[arp.stepIdx%len(arp.pattern)]
+	arp.stepIdx = (arp.stepIdx + 1) % len(arp.pattern)
+
+	return midiNote, true
+}
+
+// GetNextSample triggers the notes on the steps and returns the next sample
+// of the voices.
+func (arp *Arpeggiator) GetNextSample() float64 {
+
+	if arp.playing && float64(arp.currSample) >= arp.gateEnd {
+		arp.releaseNote()
+	}
+
+	if float64(arp.currSample) >= arp.nextStep {
+
+		arp.releaseNote()
+
+		if midiNote, ok := arp.nextNote(); ok {
+			arp.voices.NoteOnMIDI(midiNote, arp.Velocity)
+			arp.playing = true
+			arp.playingNote = midiNote
+		}
+
+		gateLength := arp.GateLength
+		if gateLength < 0 {
+			gateLength = 0
+		} else if gateLength > 1 {
+			gateLength = 1
+		}
+
+		arp.gateEnd = arp.nextStep + arp.stepLength*gateLength
+		arp.nextStep += arp.stepLength
+	}
+
+	arp.currSample++
+
+	return arp.voices.GetNextSample()
+}
+
+// Reset releases the playing note, restarts the pattern from the first step
+// and resets the voices.
+func (arp *Arpeggiator) Reset() {
+
+	arp.releaseNote()
+	arp.voices.Reset()
+
+	arp.currSample = 0
+	arp.nextStep = 0
+	arp.gateEnd = 0
+	arp.stepIdx = 0
+
+	arp.SetSeed(arp.rndSeed)
+}
@@ -35,3 +35,30 @@ func FillBuffer(buffer []float64, gen generator.Generator) []float64 {
 
 	return buffer
 }
+
+// GenerateStereo will create a left and a right buffer that are large enough
+// to hold a sample of desired size and generates the sample into them.
+func GenerateStereo(durationMS uint, gen generator.StereoGenerator) ([]float64, []float64) {
+
+	buffSize := CalcSampleLength(gen.GetSampleRate(), durationMS)
+	left := make([]float64, buffSize)
+	right := make([]float64, buffSize)
+
+	return FillStereoBuffer(left, right, gen)
+}
+
+// FillStereoBuffer generate samples into the provided left and right buffers
+// to fill up the shorter one
+func FillStereoBuffer(left, right []float64, gen generator.StereoGenerator) ([]float64, []float64) {
+
+	if left == nil || right == nil { // just to be sure
+		return []float64{}, []float64{}
+	}
+
+	gen.Reset()
+	for i := 0; i < len(left) && i < len(right); i++ {
+		left[i], right[i] = gen.GetNextStereoSample()
+	}
+
+	return left, right
+}
@@ -0,0 +1,86 @@
+package buffer
+
+import "fmt"
+
+type NoteDivision int
+
+const (
+	WholeNote NoteDivision = iota
+	HalfNote
+	QuarterNote
+	EighthNote
+	SixteenthNote
+	ThirtySecondNote
+	DottedHalfNote
+	DottedQuarterNote
+	DottedEighthNote
+	DottedSixteenthNote
+	HalfTriplet
+	QuarterTriplet
+	EighthTriplet
+	SixteenthTriplet
+)
+
+// divisionBeats holds the length of the note divisions in quarter notes.
+var divisionBeats = []float64{
+	WholeNote:           4,
+	HalfNote:            2,
+	QuarterNote:         1,
+	EighthNote:          1.0 / 2,
+	SixteenthNote:       1.0 / 4,
+	ThirtySecondNote:    1.0 / 8,
+	DottedHalfNote:      3,
+	DottedQuarterNote:   3.0 / 2,
+	DottedEighthNote:    3.0 / 4,
+	DottedSixteenthNote: 3.0 / 8,
+	HalfTriplet:         4.0 / 3,
+	QuarterTriplet:      2.0 / 3,
+	EighthTriplet:       1.0 / 3,
+	SixteenthTriplet:    1.0 / 6,
+}
+
+// GetBeats returns the length of the note division in beats, where a beat
+// is a quarter note.
+func (div NoteDivision) GetBeats() (float64, error) {
+	if div < 0 || int(div) >= len(divisionBeats) {
+		return 0, fmt.Errorf("invalid note division: %d", div)
+	}
+	return divisionBeats[div], nil
+}
+
+// CalcBeatLengthMS returns the length of a beat in milliseconds at the
+// tempo in beats per minute.
+func CalcBeatLengthMS(bpm float64) float64 {
+	return 60000 / bpm
+}
+
+// CalcDivisionLengthMS returns the length of the note division in
+// milliseconds at the tempo in beats per minute.
+func CalcDivisionLengthMS(bpm float64, div NoteDivision) (float64, error) {
+	beats, err := div.GetBeats()
+	if err != nil {
+		return 0, err
+	}
+	return beats * CalcBeatLengthMS(bpm), nil
+}
+
+// CalcDivisionFrequency returns the frequency in Hz of repeating the note
+// division at the tempo in beats per minute. Useful for tempo synced LFOs.
+func CalcDivisionFrequency(bpm float64, div NoteDivision) (float64, error) {
+	lengthMS, err := CalcDivisionLengthMS(bpm, div)
+	if err != nil {
+		return 0, err
+	}
+	return 1000 / lengthMS, nil
+}
+
+// CalcDivisionSampleLength returns how many samples are in the note
+// division at the tempo in beats per minute. The fraction is kept, so the
+// rounding errors don't add up over multiple divisions.
+func CalcDivisionSampleLength(sampleRate uint, bpm float64, div NoteDivision) (float64, error) {
+	lengthMS, err := CalcDivisionLengthMS(bpm, div)
+	if err != nil {
+		return 0, err
+	}
+	return float64(sampleRate) * lengthMS / 1000, nil
+}
@@ -0,0 +1,191 @@
+package synth
+
+import (
+	"fmt"
+	"sort"
+)
+
+type ChordType int
+
+const (
+	MajorChord ChordType = iota
+	MinorChord
+	DiminishedChord
+	AugmentedChord
+	Sus2Chord
+	Sus4Chord
+	Major6Chord
+	Minor6Chord
+	Major7Chord
+	Minor7Chord
+	Dominant7Chord
+	HalfDiminished7Chord
+	Diminished7Chord
+	MinorMajor7Chord
+	Add9Chord
+	Major9Chord
+	Minor9Chord
+	Dominant9Chord
+	PowerChord
+)
+
+// chordIntervals holds the intervals of the chords in semitones from the
+// root, in root position.
+var chordIntervals = [][]int{
+	MajorChord:           {0, 4, 7},
+	MinorChord:           {0, 3, 7},
+	DiminishedChord:      {0, 3, 6},
+	AugmentedChord:       {0, 4, 8},
+	Sus2Chord:            {0, 2, 7},
+	Sus4Chord:            {0, 5, 7},
+	Major6Chord:          {0, 4, 7, 9},
+	Minor6Chord:          {0, 3, 7, 9},
+	Major7Chord:          {0, 4, 7, 11},
+	Minor7Chord:          {0, 3, 7, 10},
+	Dominant7Chord:       {0, 4, 7, 10},
+	HalfDiminished7Chord: {0, 3, 6, 10},
+	Diminished7Chord:     {0, 3, 6, 9},
+	MinorMajor7Chord:     {0, 3, 7, 11},
+	Add9Chord:            {0, 4, 7, 14},
+	Major9Chord:          {0, 4, 7, 11, 14},
+	Minor9Chord:          {0, 3, 7, 10, 14},
+	Dominant9Chord:       {0, 4, 7, 10, 14},
+	PowerChord:           {0, 7, 12},
+}
+
+type ChordVoicing int
+
+const (
+	CloseVoicing  ChordVoicing = iota // the notes as close as possible
+	Drop2Voicing                      // the 2nd highest note dropped an octave
+	Drop3Voicing                      // the 3rd highest note dropped an octave
+	OpenVoicing                       // every other note from the bottom raised an octave
+	SpreadVoicing                     // the root dropped an octave below the rest
+)
+
+// GetChordNotes returns the MIDI notes of a chord on the root MIDI note in
+// ascending order. The inversion moves the lowest notes up an octave, 1 is
+// the first inversion, a negative one moves the highest notes down instead.
+// The voicing is applied after the inversion.
+func GetChordNotes(root uint8, chordType ChordType, inversion int, voicing ChordVoicing) ([]uint8, error) {
+
+	if chordType < 0 || int(chordType) >= len(chordIntervals) {
+		return nil, fmt.Errorf("invalid chord type: %d", chordType)
+	}
+
+	intervals := chordIntervals[chordType]
+
+	notes := make([]int, len(intervals))
+	for noteIdx, interval := range intervals {
+		notes[noteIdx] = int(root) + interval
+	}
+
+	notes = invertChord(notes, inversion)
+
+	notes, err := voiceChord(notes, voicing)
+	if err != nil {
+		return nil, err
+	}
+
+	return toMIDINotes(notes)
+}
+
+// InvertChord returns the MIDI notes of the chord in ascending order, with
+// the lowest notes moved up an octave for every inversion, or the highest
+// ones down for a negative inversion.
+func InvertChord(notes []uint8, inversion int) ([]uint8, error) {
+	return toMIDINotes(invertChord(fromMIDINotes(notes), inversion))
+}
+
+// VoiceChord returns the MIDI notes of the chord in ascending order, with
+// the voicing applied. The notes are expected in close position.
+func VoiceChord(notes []uint8, voicing ChordVoicing) ([]uint8, error) {
+	voiced, err := voiceChord(fromMIDINotes(notes), voicing)
+	if err != nil {
+		return nil, err
+	}
+	return toMIDINotes(voiced)
+}
+
+// invertChord returns the sorted notes of the inverted chord.
+func invertChord(notes []int, inversion int) []int {
+
+	inverted := make([]int, len(notes))
+	copy(inverted, notes)
+	sort.Ints(inverted)
+
+	if len(inverted) == 0 {
+		return inverted
+	}
+
+	for ; inversion > 0; inversion-- {
+		inverted = append(inverted[1:], inverted[0]+12)
+	}
+	for ; inversion < 0; inversion++ {
+		last := len(inverted) - 1
+		inverted = append([]int{inverted[last] - 12}, inverted[:last]...)
+	}
+
+	return inverted
+}
+
+// voiceChord returns the sorted notes of the chord with the voicing.
+func voiceChord(notes []int, voicing ChordVoicing) ([]int, error) {
+
+	voiced := make([]int, len(notes))
+	copy(voiced, notes)
+	sort.Ints(voiced)
+
+	switch voicing {
+	case CloseVoicing:
+
+	case Drop2Voicing:
+		if len(voiced) >= 2 {
+			voiced[len(voiced)-2] -= 12
+		}
+
+	case Drop3Voicing:
+		if len(voiced) >= 3 {
+			voiced[len(voiced)-3] -= 12
+		}
+
+	case OpenVoicing:
+		for noteIdx := 1; noteIdx < len(voiced); noteIdx += 2 {
+			voiced[noteIdx] += 12
+		}
+
+	case SpreadVoicing:
+		if len(voiced) >= 1 {
+			voiced[0] -= 12
+		}
+
+	default:
+		return nil, fmt.Errorf("invalid chord voicing: %d", voicing)
+	}
+
+	sort.Ints(voiced)
+
+	return voiced, nil
+}
+
+// fromMIDINotes returns the MIDI notes as ints.
+func fromMIDINotes(notes []uint8) []int {
+	converted := make([]int, len(notes))
+	for noteIdx, midiNote := range notes {
+		converted[noteIdx] = int(midiNote)
+	}
+	return converted
+}
+
+// toMIDINotes returns the notes as MIDI notes, or an error if one of them is
+// out of the MIDI range.
+func toMIDINotes(notes []int) ([]uint8, error) {
+	converted := make([]uint8, len(notes))
+	for noteIdx, note := range notes {
+		if note < 0 || note >= MIDINoteCount {
+			return nil, fmt.Errorf("chord note %d is out of the MIDI range", note)
+		}
+		converted[noteIdx] = uint8(note)
+	}
+	return converted, nil
+}
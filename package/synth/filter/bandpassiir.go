@@ -35,3 +35,10 @@ func (irr *BandPassIIR) Reset() {
 	irr.hp.Reset()
 	irr.lp.Reset()
 }
+
+// Clone returns an independent copy of the filter with the same settings.
+// Implements the Cloner interface.
+func (irr *BandPassIIR) Clone() Filter {
+	irrTmp := *irr
+	return &irrTmp
+}
@@ -54,3 +54,30 @@ func (cf *CompositFilter) Reset() {
 		filteChain.Reset()
 	}
 }
+
+// SetCutoff sets the cutoff frequency in Hz for the filters in the chains
+// that implement the Tunable interface. Each chain changes only its tuned
+// filter if one is selected, see FilterChain.SetTunedFilter.
+func (cf *CompositFilter) SetCutoff(freq float64) {
+	for _, filteChain := range cf.chainList {
+		filteChain.SetCutoff(freq)
+	}
+}
+
+// SetQualityFactor sets the quality factor for the filters in the chains
+// that implement the Resonant interface, see SetCutoff.
+func (cf *CompositFilter) SetQualityFactor(q float64) {
+	for _, filteChain := range cf.chainList {
+		filteChain.SetQualityFactor(q)
+	}
+}
+
+// Clone returns an independent copy of the composit filter. See
+// FilterChain.Clone for the details.
+func (cf *CompositFilter) Clone() Filter {
+	cfTmp := NewCompositFilter()
+	for chainIdx, chain := range cf.chainList {
+		cfTmp.AddFilterChain(*chain.Clone().(*FilterChain), cf.gainList[chainIdx])
+	}
+	return cfTmp
+}
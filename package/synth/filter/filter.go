@@ -9,3 +9,36 @@ type Filter interface {
 	Filter(float64) float64
 	Reset()
 }
+
+// Tunable is an interface for filters with an adjustable cutoff or center
+// frequency, so it can be modulated.
+type Tunable interface {
+	Filter
+	SetCutoff(freq float64)
+}
+
+// Resonant is an interface for filters with an adjustable quality factor,
+// so the resonance or bandwidth can be modulated.
+type Resonant interface {
+	Filter
+	SetQualityFactor(q float64)
+}
+
+// Cloner is an interface for filters that can create an independent copy
+// of themselves with the same settings. Used when a sound needs to be
+// played by multiple voices at once.
+type Cloner interface {
+	Clone() Filter
+}
+
+// Clone returns a copy of the filter if it implements the Cloner interface,
+// otherwise the same filter. Returns nil for nil.
+func Clone(filter Filter) Filter {
+	if filter == nil {
+		return nil
+	}
+	if cloner, ok := filter.(Cloner); ok {
+		return cloner.Clone()
+	}
+	return filter
+}
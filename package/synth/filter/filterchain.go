@@ -1,7 +1,11 @@
 package filter
 
+import "fmt"
+
 type FilterChain struct {
 	filterChain []Filter
+	tuneOne     bool // only the filter at tunedIdx is changed by SetCutoff and SetQualityFactor
+	tunedIdx    int
 }
 
 // NewFilterChain creates a filter chain object that chains any object that
@@ -19,9 +23,33 @@ func (fch *FilterChain) AddFilter(filter Filter) {
 	fch.filterChain = append(fch.filterChain, filter)
 }
 
-// ClearFilters simply empties the filter slice.
+// ClearFilters simply empties the filter slice, and tunes all the filters
+// again.
 func (fch *FilterChain) ClearFilters() {
 	fch.filterChain = make([]Filter, 0)
+	fch.tuneOne = false
+}
+
+// SetTunedFilter selects the only filter SetCutoff and SetQualityFactor
+// changes, by the index in the order of the AddFilter calls, e.g. the
+// low-pass of a high-pass and low-pass chain, so the high-pass stays in
+// place. Use -1 to change all of them, which is the default.
+func (fch *FilterChain) SetTunedFilter(filterIdx int) error {
+	if filterIdx < -1 || filterIdx >= len(fch.filterChain) {
+		return fmt.Errorf("invalid filter index: %d", filterIdx)
+	}
+	fch.tuneOne = filterIdx >= 0
+	fch.tunedIdx = filterIdx
+	return nil
+}
+
+// getTunedFilters returns the filters SetCutoff and SetQualityFactor
+// changes.
+func (fch *FilterChain) getTunedFilters() []Filter {
+	if !fch.tuneOne {
+		return fch.filterChain
+	}
+	return fch.filterChain[fch.tunedIdx : fch.tunedIdx+1]
 }
 
 // Filter takes a value and runs it through the filter chain.
@@ -40,3 +68,36 @@ func (fch *FilterChain) Reset() {
 		filter.Reset()
 	}
 }
+
+// SetCutoff sets the cutoff frequency in Hz for the filters in the chain
+// that implement the Tunable interface. See SetTunedFilter.
+func (fch *FilterChain) SetCutoff(freq float64) {
+	for _, filter := range fch.getTunedFilters() {
+		if tunable, ok := filter.(Tunable); ok {
+			tunable.SetCutoff(freq)
+		}
+	}
+}
+
+// SetQualityFactor sets the quality factor for the filters in the chain
+// that implement the Resonant interface. See SetTunedFilter.
+func (fch *FilterChain) SetQualityFactor(q float64) {
+	for _, filter := range fch.getTunedFilters() {
+		if resonant, ok := filter.(Resonant); ok {
+			resonant.SetQualityFactor(q)
+		}
+	}
+}
+
+// Clone returns an independent copy of the chain. The filters are cloned
+// if they implement the Cloner interface, otherwise they are shared with
+// the copy.
+func (fch *FilterChain) Clone() Filter {
+	fchTmp := NewFilterChain()
+	for _, filter := range fch.filterChain {
+		fchTmp.AddFilter(Clone(filter))
+	}
+	fchTmp.tuneOne = fch.tuneOne
+	fchTmp.tunedIdx = fch.tunedIdx
+	return fchTmp
+}
@@ -49,3 +49,10 @@ func (iir *HighPassIIR) Reset() {
 	iir.lastIn = 0
 	iir.lastOut = 0
 }
+
+// Clone returns an independent copy of the filter with the same settings.
+// Implements the Cloner interface.
+func (iir *HighPassIIR) Clone() Filter {
+	iirTmp := *iir
+	return &iirTmp
+}
@@ -47,3 +47,10 @@ func (iir *LowPassIIR) Filter(value float64) float64 {
 func (iir *LowPassIIR) Reset() {
 	iir.lastOut = 0
 }
+
+// Clone returns an independent copy of the filter with the same settings.
+// Implements the Cloner interface.
+func (iir *LowPassIIR) Clone() Filter {
+	iirTmp := *iir
+	return &iirTmp
+}
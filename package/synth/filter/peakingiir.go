@@ -120,3 +120,16 @@ func (iir *PeakingIIR) Reset() {
 		iir.outValues[i] = 0
 	}
 }
+
+// Clone returns an independent copy of the filter with the same settings.
+// Implements the Cloner interface.
+func (iir *PeakingIIR) Clone() Filter {
+	iirTmp := *iir
+	return &iirTmp
+}
+
+// SetCutoff sets the center frequency of the filter with freq in Hz.
+// Implements the Tunable interface.
+func (iir *PeakingIIR) SetCutoff(freq float64) {
+	iir.SetCenter(freq)
+}
@@ -0,0 +1,102 @@
+package filter
+
+import "math"
+
+// 2nd order resonant Low-Pass IIR using the bilinear transform as seen in
+// the Audio EQ Cookbook by Robert Bristow-Johnson
+type ResonantLowPassIIR struct {
+	sampleRate uint
+
+	cutoffFreq float64
+	q          float64
+
+	inCoeff   [3]float64
+	outCoeff  [3]float64
+	inValues  [3]float64 // 0 current
+	outValues [3]float64 // 0 current
+}
+
+// NewResonantLowPassIIR creates a new 2nd order low-pass IIR filter object
+// that implements the Filter interface. It lets frequencies through below
+// the cutoff frequency, and boosts the frequencies around the cutoff based
+// on the quality factor set with SetQualityFactor. The default quality
+// factor is 1/sqrt(2) which gives no resonance.
+// The sample rate is in Hz and can't be changed later.
+func NewResonantLowPassIIR(sampleRate uint) *ResonantLowPassIIR {
+	iirTmp := &ResonantLowPassIIR{
+		sampleRate: sampleRate,
+		cutoffFreq: float64(sampleRate) / 4,
+		q:          1 / math.Sqrt2,
+	}
+
+	iirTmp.calcCoefficients()
+
+	return iirTmp
+}
+
+// SetCutoff sets the cutoff frequency of the filter with freq in Hz.
+// 0 < freq < sampleRate/2
+func (iir *ResonantLowPassIIR) SetCutoff(freq float64) {
+	iir.cutoffFreq = freq
+
+	iir.calcCoefficients()
+}
+
+// SetQualityFactor sets the resonance at the cutoff frequency. Higher
+// values give a sharper peak. Implements the Resonant interface.
+func (iir *ResonantLowPassIIR) SetQualityFactor(q float64) {
+	iir.q = q
+
+	iir.calcCoefficients()
+}
+
+func (iir *ResonantLowPassIIR) calcCoefficients() {
+
+	w0 := Tau * iir.cutoffFreq / float64(iir.sampleRate)
+	cosW0 := math.Cos(w0)
+	alpha := math.Sin(w0) / (2 * iir.q)
+
+	// normalised by a0, the signs of the feedback are flipped
+	a0 := 1 + alpha
+
+	iir.inCoeff[0] = (1 - cosW0) / 2 / a0
+	iir.inCoeff[1] = (1 - cosW0) / a0
+	iir.inCoeff[2] = (1 - cosW0) / 2 / a0
+
+	iir.outCoeff[1] = 2 * cosW0 / a0
+	iir.outCoeff[2] = -(1 - alpha) / a0
+}
+
+// Filter takes a value and applies the low-pass filter to it.
+func (iir *ResonantLowPassIIR) Filter(value float64) float64 {
+
+	iir.inValues[2] = iir.inValues[1]
+	iir.inValues[1] = iir.inValues[0]
+	iir.inValues[0] = value
+
+	iir.outValues[2] = iir.outValues[1]
+	iir.outValues[1] = iir.outValues[0]
+
+	iir.outValues[0] = iir.inCoeff[0]*iir.inValues[0] + iir.inCoeff[1]*iir.inValues[1] + iir.inCoeff[2]*iir.inValues[2] +
+		iir.outCoeff[1]*iir.outValues[1] + iir.outCoeff[2]*iir.outValues[2]
+
+	return iir.outValues[0]
+}
+
+// Reset clears the rolling values but keeps the coefficients.
+func (iir *ResonantLowPassIIR) Reset() {
+
+	for i := 0; i < len(iir.inValues); i++ {
+		iir.inValues[i] = 0
+	}
+	for i := 0; i < len(iir.outValues); i++ {
+		iir.outValues[i] = 0
+	}
+}
+
+// Clone returns an independent copy of the filter with the same settings.
+// Implements the Cloner interface.
+func (iir *ResonantLowPassIIR) Clone() Filter {
+	iirTmp := *iir
+	return &iirTmp
+}
@@ -0,0 +1,141 @@
+package fm
+
+import "fmt"
+
+// Algorithm describes the routing between the operators. Modulators[i]
+// lists the operators that modulate operator i, Carriers lists the
+// operators mixed to the output. The routing can have loops, those use
+// the output of the previous sample. Self-modulation is set with the
+// Operator.Feedback instead.
+type Algorithm struct {
+	Modulators [][]int
+	Carriers   []int
+}
+
+// NewStackAlgorithm creates an algorithm where each operator modulates the
+// previous one, and only the first is a carrier.
+// E.g. for 4 operators: 3 -> 2 -> 1 -> 0 -> out
+func NewStackAlgorithm(operatorCount int) Algorithm {
+
+	alg := Algorithm{
+		Modulators: make([][]int, operatorCount),
+		Carriers:   []int{0},
+	}
+	for opIdx := 0; opIdx < operatorCount-1; opIdx++ {
+		alg.Modulators[opIdx] = []int{opIdx + 1}
+	}
+
+	return alg
+}
+
+// NewParallelAlgorithm creates an algorithm where all the operators are
+// carriers without modulation, like an additive organ.
+func NewParallelAlgorithm(operatorCount int) Algorithm {
+
+	alg := Algorithm{
+		Modulators: make([][]int, operatorCount),
+		Carriers:   make([]int, operatorCount),
+	}
+	for opIdx := range alg.Carriers {
+		alg.Carriers[opIdx] = opIdx
+	}
+
+	return alg
+}
+
+// NewPairsAlgorithm creates an algorithm of modulator-carrier pairs, where
+// every odd operator modulates the previous even one. The even operators
+// are the carriers. A last unpaired operator is a carrier as well.
+// E.g. for 4 operators: 1 -> 0 -> out, 3 -> 2 -> out
+func NewPairsAlgorithm(operatorCount int) Algorithm {
+
+	alg := Algorithm{
+		Modulators: make([][]int, operatorCount),
+		Carriers:   make([]int, 0),
+	}
+	for opIdx := 0; opIdx < operatorCount; opIdx += 2 {
+		alg.Carriers = append(alg.Carriers, opIdx)
+		if opIdx+1 < operatorCount {
+			alg.Modulators[opIdx] = []int{opIdx + 1}
+		}
+	}
+
+	return alg
+}
+
+// NewBranchAlgorithm creates an algorithm where all the other operators
+// modulate the first, which is the only carrier.
+// E.g. for 4 operators: 1 + 2 + 3 -> 0 -> out
+func NewBranchAlgorithm(operatorCount int) Algorithm {
+
+	alg := Algorithm{
+		Modulators: make([][]int, operatorCount),
+		Carriers:   []int{0},
+	}
+	for opIdx := 1; opIdx < operatorCount; opIdx++ {
+		alg.Modulators[0] = append(alg.Modulators[0], opIdx)
+	}
+
+	return alg
+}
+
+// validate checks if the algorithm fits the number of operators.
+func (alg Algorithm) validate(operatorCount int) error {
+
+	if len(alg.Modulators) > operatorCount {
+		return fmt.Errorf("too many modulator lists: %d for %d operators", len(alg.Modulators), operatorCount)
+	}
+	if len(alg.Carriers) == 0 {
+		return fmt.Errorf("no carriers in the algorithm")
+	}
+
+	for _, carrierIdx := range alg.Carriers {
+		if carrierIdx < 0 || carrierIdx >= operatorCount {
+			return fmt.Errorf("invalid carrier operator: %d", carrierIdx)
+		}
+	}
+
+	for opIdx, modulators := range alg.Modulators {
+		for _, modIdx := range modulators {
+			if modIdx < 0 || modIdx >= operatorCount {
+				return fmt.Errorf("invalid modulator operator %d for operator %d", modIdx, opIdx)
+			}
+			if modIdx == opIdx {
+				return fmt.Errorf("operator %d modulates itself, use Feedback instead", opIdx)
+			}
+		}
+	}
+
+	return nil
+}
+
+// getOrder returns the operator processing order, where the modulators are
+// processed before the operators they modulate. Loops are broken at the
+// first revisited operator.
+func (alg Algorithm) getOrder(operatorCount int) []int {
+
+	order := make([]int, 0, operatorCount)
+	visited := make([]bool, operatorCount)
+
+	var visit func(opIdx int)
+	visit = func(opIdx int) {
+		if visited[opIdx] {
+			return
+		}
+		visited[opIdx] = true
+
+		if opIdx < len(alg.Modulators) {
+			for _, modIdx := range alg.Modulators[opIdx] {
+				visit(modIdx)
+			}
+		}
+
+		order = append(order, opIdx)
+	}
+
+	for _, carrierIdx := range alg.Carriers {
+		visit(carrierIdx)
+	}
+
+	return order
+}
@@ -0,0 +1,169 @@
+package fm
+
+import "fmt"
+
+type Engine struct {
+	Frequency float64
+	Operators []*Operator
+
+	sampleRate uint
+	algorithm  Algorithm
+	order      []int     // processing order of the operators
+	outputs    []float64 // latest output of each operator
+
+	velocity  float64
+	gate      bool
+	lastLevel float64
+}
+
+// NewEngine creates an FM synthesis engine with the given number of
+// operators that implements the Generator interface. It defaults to the
+// stack algorithm, use SetAlgorithm to change the routing.
+// It can be played with NoteOn and NoteOff, and used as a voice in the
+// synth.VoiceManager.
+// The sample rate is in Hz and can't be changed later.
+// Returns an error if the default algorithm can't be set.
+func NewEngine(sampleRate uint, operatorCount int) (*Engine, error) {
+
+	if operatorCount < 1 {
+		operatorCount = 1
+	}
+
+	engTmp := &Engine{
+		Operators:  make([]*Operator, operatorCount),
+		sampleRate: sampleRate,
+		outputs:    make([]float64, operatorCount),
+		velocity:   1,
+	}
+
+	for opIdx := range engTmp.Operators {
+		engTmp.Operators[opIdx] = NewOperator(sampleRate)
+	}
+
+	if err := engTmp.SetAlgorithm(NewStackAlgorithm(operatorCount)); err != nil {
+		return nil, err
+	}
+
+	return engTmp, nil
+}
+
+// GetSampleRate returns the sample rate with which the engine
+// was created.
+func (eng Engine) GetSampleRate() uint {
+	return eng.sampleRate
+}
+
+// SetAlgorithm sets the routing between the operators. Returns an error if
+// the algorithm refers to operators that don't exist.
+func (eng *Engine) SetAlgorithm(alg Algorithm) error {
+
+	err := alg.validate(len(eng.Operators))
+	if err != nil {
+		return fmt.Errorf("invalid algorithm: %w", err)
+	}
+
+	eng.algorithm = alg
+	eng.order = alg.getOrder(len(eng.Operators))
+
+	return nil
+}
+
+// GetNextSample processes the operators in the order of the algorithm and
+// returns the average of the carriers.
+func (eng *Engine) GetNextSample() float64 {
+
+	for _, opIdx := range eng.order {
+
+		var phaseMod float64
+		if opIdx < len(eng.algorithm.Modulators) {
+			for _, modIdx := range eng.algorithm.Modulators[opIdx] {
+				phaseMod += eng.outputs[modIdx]
+			}
+		}
+
+		eng.outputs[opIdx] = eng.Operators[opIdx].process(eng.Frequency, phaseMod)
+	}
+
+	var sample float64
+	var level float64
+	for _, carrierIdx := range eng.algorithm.Carriers {
+		sample += eng.outputs[carrierIdx]
+		level += eng.Operators[carrierIdx].Level * eng.Operators[carrierIdx].lastEnv
+	}
+
+	carrierCount := float64(len(eng.algorithm.Carriers))
+	eng.lastLevel = level / carrierCount * eng.velocity
+
+	return sample / carrierCount * eng.velocity
+}
+
+// NoteOn restarts the engine with a new frequency in Hz and a velocity
+// [0-1] which scales the output. The envelopes hold the sustain until
+// NoteOff, and they restart from their current level. Implements the synth.Voice interface.
+func (eng *Engine) NoteOn(frequency, velocity float64) {
+	eng.Frequency = frequency
+	eng.velocity = velocity
+	eng.gate = true
+
+	for opIdx, op := range eng.Operators {
+		op.restart()
+		op.Envelope.Trigger(velocity)
+		eng.outputs[opIdx] = 0
+	}
+	eng.lastLevel = 0
+}
+
+// NoteOff starts the release phase of all the operator envelopes.
+// Implements the synth.Voice interface.
+func (eng *Engine) NoteOff() {
+	eng.gate = false
+	for _, op := range eng.Operators {
+		op.Envelope.TriggerRelease()
+	}
+}
+
+// IsActive returns true while any of the carriers are producing sound.
+// Implements the synth.Voice interface.
+func (eng Engine) IsActive() bool {
+	if eng.gate {
+		return true
+	}
+	for _, carrierIdx := range eng.algorithm.Carriers {
+		if !eng.Operators[carrierIdx].Envelope.IsFinished() {
+			return true
+		}
+	}
+	return false
+}
+
+// GetLevel returns the average carrier level of the last sample with the
+// envelopes and velocity applied. Implements the synth.Voice interface.
+func (eng Engine) GetLevel() float64 {
+	return eng.lastLevel
+}
+
+// Clone returns an independent copy of the engine with the same settings.
+func (eng *Engine) Clone() *Engine {
+	engTmp := *eng
+
+	engTmp.Operators = make([]*Operator, len(eng.Operators))
+	for opIdx, op := range eng.Operators {
+		engTmp.Operators[opIdx] = op.clone()
+	}
+	engTmp.outputs = make([]float64, len(eng.outputs))
+
+	return &engTmp
+}
+
+// Reset sets all the operators back to their starting state. A held note
+// restarts the envelopes.
+func (eng *Engine) Reset() {
+	for opIdx, op := range eng.Operators {
+		op.reset()
+		if eng.gate {
+			op.Envelope.Trigger(eng.velocity)
+		}
+		eng.outputs[opIdx] = 0
+	}
+	eng.lastLevel = 0
+}
@@ -0,0 +1,88 @@
+package fm
+
+import (
+	"math"
+
+	"github.com/rawbits2010/LibBitDauer/package/synth/generator"
+	"github.com/rawbits2010/LibBitDauer/package/synth/modulator"
+)
+
+type Operator struct {
+	Ratio          float64 // multiplier of the engine frequency
+	FixedFrequency float64 // in Hz, used instead of Ratio when >0
+	Level          float64 // output level for carriers, modulation index for modulators
+	Feedback       float64 // self-modulation amount [0-1]
+	Envelope       *modulator.ADSR
+
+	sampleRate uint
+	phase      float64    // [0-1)
+	lastOut    [2]float64 // 0 is the latest, used for feedback
+	lastEnv    float64    // latest envelope value
+}
+
+// NewOperator creates a new FM operator which is a phase-accumulating sine
+// oscillator with its own envelope. When it modulates another operator, its
+// output is added to the phase of the other in radians, so the level works
+// as the modulation index.
+// The envelope holds at full level until released by default.
+// The sample rate is in Hz and can't be changed later.
+func NewOperator(sampleRate uint) *Operator {
+
+	opTmp := &Operator{
+		Ratio:      1,
+		Level:      1,
+		Envelope:   modulator.NewADSR(sampleRate),
+		sampleRate: sampleRate,
+	}
+	opTmp.Envelope.ManualSustain = true
+
+	return opTmp
+}
+
+// getFrequency returns the frequency of the operator in Hz for the engine
+// frequency.
+func (op Operator) getFrequency(baseFreq float64) float64 {
+	if op.FixedFrequency > 0 {
+		return op.FixedFrequency
+	}
+	return baseFreq * op.Ratio
+}
+
+// process calculates the next output of the operator with the phase
+// modulation in radians, and advances the phase.
+func (op *Operator) process(baseFreq float64, phaseMod float64) float64 {
+
+	feedback := op.Feedback * (op.lastOut[0] + op.lastOut[1]) / 2 * math.Pi
+
+	op.lastEnv = op.Envelope.GetNextSample()
+	out := math.Sin(generator.Tau*op.phase+phaseMod+feedback) * op.Level * op.lastEnv
+
+	op.lastOut[1] = op.lastOut[0]
+	op.lastOut[0] = out
+
+	op.phase += op.getFrequency(baseFreq) / float64(op.sampleRate)
+	op.phase -= math.Floor(op.phase)
+
+	return out
+}
+
+// clone returns an independent copy of the operator.
+func (op *Operator) clone() *Operator {
+	opTmp := *op
+	opTmp.Envelope = op.Envelope.Clone().(*modulator.ADSR)
+	return &opTmp
+}
+
+// reset sets the operator back to it's starting state.
+func (op *Operator) reset() {
+	op.restart()
+	op.Envelope.Reset()
+}
+
+// restart sets the operator back to it's starting phase, but keeps the
+// envelope going.
+func (op *Operator) restart() {
+	op.phase = 0
+	op.lastOut = [2]float64{}
+	op.lastEnv = 0
+}
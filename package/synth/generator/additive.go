@@ -0,0 +1,199 @@
+package generator
+
+import (
+	"fmt"
+	"math"
+)
+
+type Partial struct {
+	Ratio     float64   // multiplier of the fundamental frequency
+	Amplitude float64   // [0-1]
+	Phase     float64   // starting phase in degrees
+	Envelope  Generator // optional envelope of the partial, can be nil
+}
+
+type AdditiveGenerator struct {
+	Frequency  float64 // of the fundamental
+	Brightness float64 // [0-1] lower values fade the upper partials, 1 is unchanged
+	Tilt       float64 // in dB/octave relative to the fundamental, negative is darker
+
+	sampleRate uint
+	partials   []Partial
+	phases     []float64 // [0-1) for each partial
+
+	gains          []float64 // amplitudes with brightness and tilt applied
+	gainBrightness float64   // the values the gains were calculated with
+	gainTilt       float64
+	gainSum        float64
+}
+
+// NewAdditiveGenerator creates a new additive generator that implements the
+// Generator interface. It sums sine partials at multiples of the
+// fundamental frequency, added with AddPartial. Partials above the Nyquist
+// frequency are skipped to avoid aliasing. The output is normalized by the
+// sum of the partial amplitudes.
+// The sample rate is in Hz and can't be changed later.
+func NewAdditiveGenerator(sampleRate uint) *AdditiveGenerator {
+
+	agTmp := &AdditiveGenerator{
+		Brightness: 1,
+		sampleRate: sampleRate,
+		partials:   make([]Partial, 0),
+		phases:     make([]float64, 0),
+	}
+
+	return agTmp
+}
+
+// GetSampleRate returns the sample rate with which the generator
+// was created.
+func (ag AdditiveGenerator) GetSampleRate() uint {
+	return ag.sampleRate
+}
+
+// AddPartial adds a partial to the generator.
+func (ag *AdditiveGenerator) AddPartial(partial Partial) {
+	ag.partials = append(ag.partials, partial)
+	ag.phases = append(ag.phases, normalizePhaseDeg(partial.Phase))
+	ag.gains = nil
+}
+
+// AddPartials adds partials without envelopes from matching frequency
+// ratio and amplitude lists, like the harmonics measured by the assay
+// package.
+func (ag *AdditiveGenerator) AddPartials(ratios, amplitudes []float64) {
+	for partialIdx, ratio := range ratios {
+		if partialIdx >= len(amplitudes) {
+			break
+		}
+		ag.AddPartial(Partial{Ratio: ratio, Amplitude: amplitudes[partialIdx]})
+	}
+}
+
+// AddHarmonics adds partials at the integer multiples of the fundamental
+// with the given amplitudes, starting with the fundamental.
+func (ag *AdditiveGenerator) AddHarmonics(amplitudes []float64) {
+	for harmonicIdx, amplitude := range amplitudes {
+		ag.AddPartial(Partial{Ratio: float64(harmonicIdx + 1), Amplitude: amplitude})
+	}
+}
+
+// GetPartials returns a copy of the partials in the order they were added.
+// Use SetPartial to change them.
+func (ag AdditiveGenerator) GetPartials() []Partial {
+	return append([]Partial(nil), ag.partials...)
+}
+
+// SetPartial replaces the partial at the index. The phase of the partial
+// keeps running, the new starting phase is used from the next reset.
+func (ag *AdditiveGenerator) SetPartial(partialIdx int, partial Partial) error {
+	if partialIdx < 0 || partialIdx >= len(ag.partials) {
+		return fmt.Errorf("invalid partial index: %d", partialIdx)
+	}
+	ag.partials[partialIdx] = partial
+	ag.gains = nil
+	return nil
+}
+
+// ClearPartials simply empties the partial slice.
+func (ag *AdditiveGenerator) ClearPartials() {
+	ag.partials = make([]Partial, 0)
+	ag.phases = make([]float64, 0)
+	ag.gains = nil
+}
+
+// normalizePhaseDeg converts the phase in degrees to a [0-1) phase.
+func normalizePhaseDeg(angleDeg float64) float64 {
+	phase := angleDeg / 360.0
+	return phase - math.Floor(phase)
+}
+
+// updateGains recalculates the partial gains if the brightness or tilt
+// changed since the last time.
+func (ag *AdditiveGenerator) updateGains() {
+
+	if ag.gains != nil && ag.gainBrightness == ag.Brightness && ag.gainTilt == ag.Tilt {
+		return
+	}
+
+	ag.gains = make([]float64, len(ag.partials))
+	ag.gainSum = 0
+	for partialIdx, partial := range ag.partials {
+
+		gain := partial.Amplitude
+		if partial.Ratio > 0 {
+			gain *= math.Pow(10, ag.Tilt*math.Log2(partial.Ratio)/20)
+		}
+		if partial.Ratio > 1 {
+			gain *= math.Pow(ag.Brightness, partial.Ratio-1)
+		}
+
+		ag.gains[partialIdx] = gain
+		ag.gainSum += math.Abs(gain)
+	}
+
+	ag.gainBrightness = ag.Brightness
+	ag.gainTilt = ag.Tilt
+}
+
+// GetNextSample advances the phases and returns the next sample as the sum
+// of the partials.
+func (ag *AdditiveGenerator) GetNextSample() float64 {
+
+	ag.updateGains()
+
+	nyquist := float64(ag.sampleRate) / 2
+
+	var sample float64
+	for partialIdx, partial := range ag.partials {
+
+		freq := ag.Frequency * partial.Ratio
+
+		envelope := 1.0
+		if partial.Envelope != nil {
+			envelope = partial.Envelope.GetNextSample()
+		}
+
+		if freq < nyquist {
+			sample += math.Sin(Tau*ag.phases[partialIdx]) * ag.gains[partialIdx] * envelope
+		}
+
+		ag.phases[partialIdx] += freq / float64(ag.sampleRate)
+		ag.phases[partialIdx] -= math.Floor(ag.phases[partialIdx])
+	}
+
+	if ag.gainSum > 0 {
+		sample /= ag.gainSum
+	}
+
+	return sample
+}
+
+// Clone returns an independent copy of the generator with the same settings.
+// The partial envelopes are cloned if they implement the Cloner interface,
+// otherwise they are shared with the copy.
+func (ag *AdditiveGenerator) Clone() Generator {
+	agTmp := *ag
+
+	agTmp.partials = make([]Partial, len(ag.partials))
+	for partialIdx, partial := range ag.partials {
+		if cloner, ok := partial.Envelope.(Cloner); ok {
+			partial.Envelope = cloner.Clone()
+		}
+		agTmp.partials[partialIdx] = partial
+	}
+	agTmp.phases = append([]float64(nil), ag.phases...)
+	agTmp.gains = nil
+
+	return &agTmp
+}
+
+// Reset sets the generator back to it's starting state.
+func (ag *AdditiveGenerator) Reset() {
+	for partialIdx, partial := range ag.partials {
+		ag.phases[partialIdx] = normalizePhaseDeg(partial.Phase)
+		if partial.Envelope != nil {
+			partial.Envelope.Reset()
+		}
+	}
+}
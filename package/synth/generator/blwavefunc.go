@@ -0,0 +1,97 @@
+package generator
+
+import "math"
+
+// BandLimitedWaveFunction is a wave function that also gets the angle step
+// of the current frequency, so it can smooth the discontinuities of the
+// waveform to reduce aliasing. Use this in a FunctionGenerator with
+// SetBandLimitedFunction.
+type BandLimitedWaveFunction func(angle, angleStep float64) float64
+
+// polyBLEP returns the polynomial band-limited step residual for the phase
+// t [0-1) and phase increment dt, to be added at a jump of +2.
+func polyBLEP(t, dt float64) float64 {
+
+	if dt <= 0 {
+		return 0
+	}
+
+	if t < dt {
+		t /= dt
+		return t + t - t*t - 1
+	}
+	if t > 1-dt {
+		t = (t - 1) / dt
+		return t*t + t + t + 1
+	}
+
+	return 0
+}
+
+// polyBLAMP returns the polynomial band-limited ramp residual for the phase
+// t [0-1) and phase increment dt, to be scaled by the change of the slope.
+func polyBLAMP(t, dt float64) float64 {
+
+	if dt <= 0 {
+		return 0
+	}
+
+	if t < dt {
+		t = t/dt - 1
+		return -1 / 3.0 * t * t * t
+	}
+	if t > 1-dt {
+		t = (t-1)/dt + 1
+		return 1 / 3.0 * t * t * t
+	}
+
+	return 0
+}
+
+// wrapPhase returns the phase in the [0-1) range.
+func wrapPhase(t float64) float64 {
+	return t - math.Floor(t)
+}
+
+// BLSquareFunction returns the value [-1-1] for the given angle in radians,
+// using a square function band-limited with PolyBLEP.
+func BLSquareFunction(angle, angleStep float64) float64 {
+	t := angle / Tau
+	dt := angleStep / Tau
+	return SquareFunction(angle) + polyBLEP(t, dt) - polyBLEP(wrapPhase(t+0.5), dt)
+}
+
+// BLSawtoothFunction returns the value [-1-1] for the given angle in radians,
+// using a sawtooth function band-limited with PolyBLEP.
+func BLSawtoothFunction(angle, angleStep float64) float64 {
+	t := angle / Tau
+	dt := angleStep / Tau
+	return SawtoothFunction(angle) - polyBLEP(t, dt)
+}
+
+// BLRevSawtoothFunction returns the value [-1-1] for the given angle in
+// radians, using a reverse sawtooth function band-limited with PolyBLEP.
+func BLRevSawtoothFunction(angle, angleStep float64) float64 {
+	return -BLSawtoothFunction(angle, angleStep)
+}
+
+// BLTriangleFunction returns the value [-1-1] for the given angle in radians,
+// using a triangle function band-limited with PolyBLAMP.
+func BLTriangleFunction(angle, angleStep float64) float64 {
+	t := angle / Tau
+	dt := angleStep / Tau
+	return TriangleFunction(angle) - 4*dt*(polyBLAMP(t, dt)-polyBLAMP(wrapPhase(t+0.5), dt))
+}
+
+// BLPulseFunction returns the value [-1-1] for the phase t [0-1) with the
+// duty cycle [0-1] and phase increment dt, using a pulse function
+// band-limited with PolyBLEP.
+func BLPulseFunction(t, dutyCycle, dt float64) float64 {
+
+	sample := -1.0
+	if t < dutyCycle {
+		sample = 1
+	}
+
+	return sample + polyBLEP(t, dt) - polyBLEP(wrapPhase(t-dutyCycle), dt)
+}
@@ -11,3 +11,37 @@ type Generator interface {
 	GetNextSample() float64
 	Reset() // call this before a new playback
 }
+
+// Cloner is an interface for generators that can create an independent copy
+// of themselves with the same settings. Used when a sound needs to be played
+// by multiple voices at once.
+type Cloner interface {
+	Clone() Generator
+}
+
+// SyncSource is an interface for generators that report when they complete
+// a cycle, so others can hard sync to them.
+type SyncSource interface {
+	Generator
+	// CycleWrapped returns true if the last GetNextSample call completed a
+	// cycle, with the fraction [0-1) of the sample step after the wrap.
+	CycleWrapped() (bool, float64)
+}
+
+// cloneSyncSource returns a clone of the sync source if it implements the
+// Cloner interface and the clone is a SyncSource too, otherwise the same
+// sync source.
+func cloneSyncSource(master SyncSource) SyncSource {
+	if cloner, ok := master.(Cloner); ok {
+		if masterTmp, ok := cloner.Clone().(SyncSource); ok {
+			return masterTmp
+		}
+	}
+	return master
+}
+
+// StereoGenerator is an interface for generators that can output 2 channels.
+type StereoGenerator interface {
+	Generator
+	GetNextStereoSample() (float64, float64) // left, right
+}
@@ -2,6 +2,8 @@ package generator
 
 import (
 	"fmt"
+	"math"
+	"math/bits"
 	"math/rand"
 	"time"
 
@@ -16,56 +18,155 @@ const (
 	WhiteNoise
 	BlueNoise
 	VioletNoise
+	VelvetNoise
+	GreyNoise
+	DigitalNoise
 )
 
+// BrownNoise is the same as red noise.
+const BrownNoise = RedNoise
+
+// noiseRMS is the RMS level of the colored, grey and digital noises, about
+// -12 dBFS, so the peaks rarely go over 1.
+const noiseRMS = 0.25
+
+// pinkRowCount is the number of random rows added up for pink noise, each
+// one covers an octave.
+const pinkRowCount = 16
+
+// brownCutoff is the frequency in Hz below which red noise stops rising,
+// so it doesn't drift away.
+const brownCutoff = 10
+
+// defaultVelvetDensity is the number of impulses per second in velvet noise.
+const defaultVelvetDensity = 2000
+
 type NoiseGenerator struct {
+	VelvetDensity float64 // impulses per second in velvet noise (0-sampleRate], 0 or less is silence
+	DigitalClock  float64 // clock of the digital noise in Hz, 0 is the sample rate
+	DigitalShort  bool    // 7 bit digital noise with a metallic tone instead of 15 bit
+
 	noiseType  NoiseType
 	rndSeed    int64
-	rndFunc    rand.Rand
+	rndFunc    *rand.Rand
 	sampleRate uint
-	filter     filter.Filter
+	gain       float64       // calibrates the output level
+	filter     filter.Filter // shapes the grey noise
+
+	pinkRows    [pinkRowCount]float64
+	pinkSum     float64
+	pinkCounter uint32
+
+	brownLeak float64
+	lastValue float64 // the state of red noise, the previous sample for the others
+
+	velvetCurrS   uint    // position in the current impulse period
+	velvetPeriodS uint    // length of the current impulse period, 0 before the first
+	velvetImpulse uint    // position of the impulse in the current period
+	velvetFrac    float64 // the fraction of the period length carried over
+
+	lfsr         uint16
+	digitalPhase float64
 }
 
 // NewNoiseGenerator creates a noise generator object the implements the
 // Generator interface. You can select the noise type with SetNoiseType.
+// The colored noises are generated with cheap algorithms, and calibrated to
+// the same RMS level, so they can be swapped without a jump in loudness.
+// White noise uses the full [-1-1] range. Velvet noise is not calibrated,
+// its impulses are full scale, so its RMS level is about
+// sqrt(VelvetDensity/sampleRate), e.g. 0.21 at the default 2000 impulses
+// per second at 44.1 kHz.
 // The sample rate is in Hz and can't be changed later.
 func NewNoiseGenerator(sampleRate uint) *NoiseGenerator {
 	ngTmp := &NoiseGenerator{
-		sampleRate: sampleRate,
-		noiseType:  WhiteNoise,
-		filter:     nil,
+		VelvetDensity: defaultVelvetDensity,
+		sampleRate:    sampleRate,
+		noiseType:     WhiteNoise,
+		gain:          1,
+		filter:        nil,
+		brownLeak:     math.Exp(-filter.Tau * brownCutoff / float64(sampleRate)),
 	}
 	ngTmp.SetSeed(time.Now().UnixNano())
 
 	return ngTmp
 }
 
-// SetSeed sets the seed for the random generator for consistency.
+// GetSampleRate returns the sample rate with which the generator
+// was created.
+func (ng NoiseGenerator) GetSampleRate() uint {
+	return ng.sampleRate
+}
+
+// SetSeed sets the seed for the random generator for consistency, and
+// restarts the noise.
 func (ng *NoiseGenerator) SetSeed(seed int64) {
 	ng.rndSeed = seed
-	ng.rndFunc = *rand.New(rand.NewSource(seed))
+	ng.rndFunc = rand.New(rand.NewSource(seed))
+
+	for rowIdx := range ng.pinkRows {
+		ng.pinkRows[rowIdx] = ng.getNextValue()
+	}
+	ng.pinkSum = 0
+	for _, row := range ng.pinkRows {
+		ng.pinkSum += row
+	}
+	ng.pinkCounter = 0
+
+	ng.lastValue = 0
+	ng.velvetCurrS = 0
+	ng.velvetPeriodS = 0
+	ng.velvetFrac = 0
+
+	// any state but 0 works
+	ng.lfsr = uint16(seed)&0x7fff | 1
+	ng.digitalPhase = 0
+
+	if ng.filter != nil {
+		ng.filter.Reset()
+	}
 }
 
-// SetNoiseType selects the noise type to use. The different types are
-// implemented by filtering white noise and are calculation heavy.
+// SetNoiseType selects the noise type to use:
+//   - red (brown): white noise through a leaky integrator, -6 dB/octave
+//   - pink: Voss-McCartney algorithm, -3 dB/octave
+//   - blue: differentiated pink noise, +3 dB/octave
+//   - violet: differentiated white noise, +6 dB/octave
+//   - velvet: random sign full scale impulses, one in every period set by
+//     VelvetDensity, the level depends on the density
+//   - grey: white noise shaped by an approximate inverted equal-loudness curve
+//   - digital: pseudo random bits from a linear-feedback shift register
 func (ng *NoiseGenerator) SetNoiseType(noiseType NoiseType) error {
 
-	// setup filters as seen at https://github.com/DIDAVA/dNoise
+	ng.filter = nil
+
+	// the variance of the uniform white noise is 1/3
 	switch noiseType {
 	case RedNoise:
-		ng.filter = generateFilterChain(15, -6, ng.sampleRate)
+		ng.gain = noiseRMS * math.Sqrt(3*(1-ng.brownLeak*ng.brownLeak))
 
 	case PinkNoise:
-		ng.filter = generateFilterChain(15, -3, ng.sampleRate)
+		ng.gain = noiseRMS / math.Sqrt((pinkRowCount+1)/3.0)
 
 	case WhiteNoise:
-		ng.filter = nil
+		ng.gain = 1
 
 	case BlueNoise:
-		ng.filter = generateFilterChain(15, 3, ng.sampleRate)
+		// every row changes at half the rate of the previous one
+		ng.gain = noiseRMS / math.Sqrt(2.0/3.0*(2-math.Pow(2, -pinkRowCount)))
 
 	case VioletNoise:
-		ng.filter = generateFilterChain(51, 6, ng.sampleRate)
+		ng.gain = noiseRMS / math.Sqrt(2.0/3.0)
+
+	case VelvetNoise:
+		ng.gain = 1
+
+	case GreyNoise:
+		ng.filter = generateGreyFilter(ng.sampleRate)
+		ng.gain = noiseRMS / math.Sqrt(calcPowerGain(ng.filter, ng.sampleRate)/3)
+
+	case DigitalNoise:
+		ng.gain = noiseRMS
 
 	default:
 		return fmt.Errorf("invalid noise type: %d", noiseType)
@@ -73,55 +174,203 @@ func (ng *NoiseGenerator) SetNoiseType(noiseType NoiseType) error {
 
 	ng.noiseType = noiseType
 
+	// the state of the previous type would cause a jump
+	ng.lastValue = 0
+
 	return nil
 }
 
-func generateFilterChain(start, step int, sampleRate uint) *filter.FilterChain {
+// GetNoiseType returns the selected noise type.
+func (ng NoiseGenerator) GetNoiseType() NoiseType {
+	return ng.noiseType
+}
 
-	fch := *filter.NewFilterChain()
+// generateGreyFilter returns a filter chain that boosts the low and high
+// frequencies where the hearing is less sensitive, and cuts a bit around
+// 3 kHz where it is the most sensitive.
+func generateGreyFilter(sampleRate uint) *filter.FilterChain {
+
+	fch := filter.NewFilterChain()
+
+	low := filter.NewPeakingIIR(sampleRate)
+	low.SetCenter(40)
+	low.SetQualityFactor(0.4)
+	low.SetGaindB(18)
+	fch.AddFilter(low)
+
+	presence := filter.NewPeakingIIR(sampleRate)
+	presence.SetCenter(3000)
+	presence.SetQualityFactor(0.8)
+	presence.SetGaindB(-3)
+	fch.AddFilter(presence)
+
+	highFreq := math.Min(14000, float64(sampleRate)*0.4)
+	high := filter.NewPeakingIIR(sampleRate)
+	high.SetCenter(highFreq)
+	high.SetQualityFactor(0.7)
+	high.SetGaindB(6)
+	fch.AddFilter(high)
+
+	return fch
+}
 
-	lp := filter.NewLowPassIIR(sampleRate)
-	lp.SetCutoff(22050)
-	fch.AddFilter(lp)
+// calcPowerGain returns how much the filter changes the power of white
+// noise, from the energy of its impulse response in the first second.
+func calcPowerGain(flt filter.Filter, sampleRate uint) float64 {
 
-	hp := filter.NewHighPassIIR(sampleRate)
-	hp.SetCutoff(0)
-	fch.AddFilter(hp)
+	flt.Reset()
 
-	// 31 lowshelf, 22050 highshelf
-	octaves := []float64{31, 62, 125, 250, 500, 1000, 2000, 4000, 8000, 16000}
-	for freqIdx, freq := range octaves {
+	energy := 0.0
+	value := 1.0
+	for sampleIdx := uint(0); sampleIdx < sampleRate; sampleIdx++ {
+		out := flt.Filter(value)
+		energy += out * out
+		value = 0
+	}
 
-		filter := filter.NewPeakingIIR(sampleRate)
-		filter.SetCenter(freq)
-		filter.SetQualityFactor(0.45)
-		filter.SetGaindB(float64(start + (freqIdx * step)))
+	flt.Reset()
 
-		fch.AddFilter(filter)
+	return energy
+}
+
+// GetNextSample returns the next value for the set type of noise.
+func (ng *NoiseGenerator) GetNextSample() float64 {
+
+	switch ng.noiseType {
+
+	case RedNoise:
+		ng.lastValue = ng.brownLeak*ng.lastValue + ng.getNextValue()
+		return ng.lastValue * ng.gain
+
+	case PinkNoise:
+		return ng.getNextPink() * ng.gain
+
+	case BlueNoise:
+		pink := ng.getNextPink()
+		value := pink - ng.lastValue
+		ng.lastValue = pink
+		return value * ng.gain
+
+	case VioletNoise:
+		white := ng.getNextValue()
+		value := white - ng.lastValue
+		ng.lastValue = white
+		return value * ng.gain
+
+	case VelvetNoise:
+		return ng.getNextVelvet()
+
+	case GreyNoise:
+		return ng.filter.Filter(ng.getNextValue()) * ng.gain
+
+	case DigitalNoise:
+		return ng.getNextDigital() * ng.gain
 	}
 
-	return &fch
+	return ng.getNextValue()
 }
 
-// GetNextSample returns the next value for the set type of noise.
-func (ng NoiseGenerator) GetNextSample() float64 {
+func (ng *NoiseGenerator) getNextValue() float64 {
+	return ng.rndFunc.Float64()*2 - 1
+}
+
+// getNextPink updates one of the rows, which are updated at half the rate
+// of the previous one, and returns their sum with a white noise sample.
+func (ng *NoiseGenerator) getNextPink() float64 {
+
+	counter := ng.pinkCounter
+	ng.pinkCounter++
+
+	if counter != 0 {
+		rowIdx := bits.TrailingZeros32(counter)
+		if rowIdx < pinkRowCount {
+			ng.pinkSum -= ng.pinkRows[rowIdx]
+			ng.pinkRows[rowIdx] = ng.getNextValue()
+			ng.pinkSum += ng.pinkRows[rowIdx]
+		}
+	}
+
+	return ng.pinkSum + ng.getNextValue()
+}
+
+// getNextVelvet returns a random sign impulse at a random position in
+// every period, and 0 otherwise. The fractional period lengths are
+// carried over, so the density is exact. Returns silence if the density is
+// 0 or less.
+func (ng *NoiseGenerator) getNextVelvet() float64 {
 
-	value := ng.getNextValue()
+	if ng.VelvetDensity <= 0 {
+		return 0
+	}
+
+	if ng.velvetCurrS >= ng.velvetPeriodS {
+
+		// at least one impulse per period, and the period must fit an int
+		periodS := float64(ng.sampleRate)/ng.VelvetDensity + ng.velvetFrac
+		periodS = math.Min(math.Max(periodS, 1), math.MaxInt32)
+
+		ng.velvetPeriodS = uint(periodS)
+		ng.velvetFrac = periodS - float64(ng.velvetPeriodS)
+		ng.velvetImpulse = uint(ng.rndFunc.Intn(int(ng.velvetPeriodS)))
+		ng.velvetCurrS = 0
+	}
 
-	if ng.noiseType == WhiteNoise {
-		return value
+	value := 0.0
+	if ng.velvetCurrS == ng.velvetImpulse {
+		value = 1
+		if ng.rndFunc.Intn(2) == 0 {
+			value = -1
+		}
 	}
 
-	value = ng.filter.Filter(value) / 4.0
+	ng.velvetCurrS++
 
 	return value
 }
 
-func (ng NoiseGenerator) getNextValue() float64 {
-	return ng.rndFunc.Float64()*2 - 1
+// getNextDigital clocks the shift register at the DigitalClock rate, and
+// returns the lowest bit as -1 or 1. The taps are the same as in the noise
+// channel of the NES.
+func (ng *NoiseGenerator) getNextDigital() float64 {
+
+	clock := ng.DigitalClock
+	if clock <= 0 {
+		clock = float64(ng.sampleRate)
+	}
+
+	ng.digitalPhase += clock / float64(ng.sampleRate)
+	for ng.digitalPhase >= 1 {
+		ng.digitalPhase -= 1
+
+		tap := uint16(1)
+		if ng.DigitalShort {
+			tap = 6
+		}
+		feedback := (ng.lfsr ^ (ng.lfsr >> tap)) & 1
+		ng.lfsr = ng.lfsr>>1 | feedback<<14
+	}
+
+	if ng.lfsr&1 == 1 {
+		return 1
+	}
+	return -1
+}
+
+// Clone returns a new noise generator with the same seed and settings.
+// Implements the Cloner interface.
+func (ng *NoiseGenerator) Clone() Generator {
+	ngTmp := NewNoiseGenerator(ng.sampleRate)
+	ngTmp.VelvetDensity = ng.VelvetDensity
+	ngTmp.DigitalClock = ng.DigitalClock
+	ngTmp.DigitalShort = ng.DigitalShort
+	ngTmp.SetNoiseType(ng.noiseType)
+	ngTmp.SetSeed(ng.rndSeed)
+
+	return ngTmp
 }
 
-// Reset re-initializes the random generator with the set seed.
+// Reset re-initializes the random generator with the set seed, so the
+// same noise is generated again.
 func (ng *NoiseGenerator) Reset() {
 	ng.SetSeed(ng.rndSeed)
 }
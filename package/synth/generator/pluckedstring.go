@@ -0,0 +1,192 @@
+package generator
+
+import "math"
+
+// silenceLevel is the level below which a decaying sound counts as silent.
+const silenceLevel = 0.0001
+
+type PluckedString struct {
+	Frequency    float64
+	Decay        float64         // [0-1] loop gain per cycle, lower is shorter
+	ReleaseDecay float64         // [0-1] loop gain after NoteOff, mutes the string
+	Stretch      float64         // [0-1] decay stretch factor, 0.5 is the original algorithm
+	Damping      float64         // [0-1) extra low-pass in the loop, higher is darker
+	PickPosition float64         // [0-1) relative position of the pick, 0 disables it
+	Noise        *NoiseGenerator // the excitation source
+
+	sampleRate uint
+	velocity   float64
+	gate       bool
+	released   bool // NoteOff was called, the ReleaseDecay applies
+	level      float64
+
+	delayLine []float64
+	delayIdx  int
+	apCoeff   float64 // fractional delay allpass
+	apLastIn  float64
+	apLastOut float64
+	lastOut   float64 // for the stretch filter
+	dampOut   float64 // for the damping filter
+}
+
+// NewPluckedString creates a Karplus-Strong plucked string generator that
+// implements the Generator interface. The string is plucked with a burst
+// from the noise generator on Reset and NoteOn, so set the seed of Noise
+// for reproducible sounds. The delay line is tuned with an allpass filter
+// for accurate pitch. Frequency changes take effect at the next pluck.
+// It can be played with NoteOn and NoteOff, and used as a voice in the
+// synth.VoiceManager.
+// The sample rate is in Hz and can't be changed later.
+func NewPluckedString(sampleRate uint) *PluckedString {
+
+	psTmp := &PluckedString{
+		Decay:        0.996,
+		ReleaseDecay: 0.9,
+		Stretch:      0.5,
+		Noise:        NewNoiseGenerator(sampleRate),
+		sampleRate:   sampleRate,
+		velocity:     1,
+	}
+
+	return psTmp
+}
+
+// GetSampleRate returns the sample rate with which the generator
+// was created.
+func (ps PluckedString) GetSampleRate() uint {
+	return ps.sampleRate
+}
+
+// pluck tunes the delay line to the frequency and fills it with noise.
+func (ps *PluckedString) pluck() {
+
+	ps.apLastIn = 0
+	ps.apLastOut = 0
+	ps.lastOut = 0
+	ps.dampOut = 0
+	ps.delayIdx = 0
+
+	if ps.Frequency <= 0 {
+		ps.delayLine = nil
+		ps.level = 0
+		return
+	}
+
+	damping := math.Min(math.Max(ps.Damping, 0), 0.99)
+	stretch := math.Min(math.Max(ps.Stretch, 0), 1)
+
+	// the loop filters delay the signal too, the allpass makes up the rest
+	omega := Tau * ps.Frequency / float64(ps.sampleRate)
+	stretchDelay := math.Atan2(stretch*math.Sin(omega), (1-stretch)+stretch*math.Cos(omega)) / omega
+	dampingDelay := math.Atan2(damping*math.Sin(omega), 1-damping*math.Cos(omega)) / omega
+	period := float64(ps.sampleRate)/ps.Frequency - stretchDelay - dampingDelay
+	delayLen := int(math.Floor(period))
+	fraction := period - float64(delayLen)
+	if fraction < 0.5 {
+		delayLen--
+		fraction++
+	}
+	if delayLen < 1 {
+		delayLen = 1
+	}
+	ps.apCoeff = math.Sin((1-fraction)*omega/2) / math.Sin((1+fraction)*omega/2)
+
+	ps.delayLine = make([]float64, delayLen)
+	for sampleIdx := range ps.delayLine {
+		ps.delayLine[sampleIdx] = ps.Noise.GetNextSample() * ps.velocity
+	}
+
+	// a comb filter cancels the harmonics that have a node at the pick
+	if ps.PickPosition > 0 && ps.PickPosition < 1 && delayLen > 1 {
+		pickDelay := int(math.Round(ps.PickPosition * float64(delayLen)))
+		pickDelay = min(max(pickDelay, 1), delayLen-1)
+		for sampleIdx := len(ps.delayLine) - 1; sampleIdx >= pickDelay; sampleIdx-- {
+			ps.delayLine[sampleIdx] -= ps.delayLine[sampleIdx-pickDelay]
+		}
+	}
+
+	ps.level = ps.velocity
+}
+
+// GetNextSample returns the next sample of the string.
+func (ps *PluckedString) GetNextSample() float64 {
+
+	if len(ps.delayLine) == 0 {
+		return 0
+	}
+
+	out := ps.delayLine[ps.delayIdx]
+
+	stretch := math.Min(math.Max(ps.Stretch, 0), 1)
+	damping := math.Min(math.Max(ps.Damping, 0), 0.99)
+
+	value := (1-stretch)*out + stretch*ps.lastOut
+	ps.lastOut = out
+
+	value = (1-damping)*value + damping*ps.dampOut
+	ps.dampOut = value
+
+	apOut := ps.apCoeff*value + ps.apLastIn - ps.apCoeff*ps.apLastOut
+	ps.apLastIn = value
+	ps.apLastOut = apOut
+
+	decay := ps.Decay
+	if ps.released && ps.ReleaseDecay < decay {
+		decay = ps.ReleaseDecay
+	}
+
+	ps.delayLine[ps.delayIdx] = apOut * decay
+	ps.delayIdx++
+	if ps.delayIdx >= len(ps.delayLine) {
+		ps.delayIdx = 0
+	}
+
+	ps.level = math.Max(math.Abs(out), ps.level*0.999)
+
+	return out
+}
+
+// NoteOn plucks the string with a new frequency in Hz and a velocity [0-1]
+// which scales the excitation. Implements the synth.Voice interface.
+func (ps *PluckedString) NoteOn(frequency, velocity float64) {
+	ps.Frequency = frequency
+	ps.velocity = velocity
+	ps.gate = true
+	ps.released = false
+	ps.pluck()
+}
+
+// NoteOff switches to the ReleaseDecay to mute the string. Implements the
+// synth.Voice interface.
+func (ps *PluckedString) NoteOff() {
+	ps.gate = false
+	ps.released = true
+}
+
+// IsActive returns true while the note is held or the string is still
+// audible. Implements the synth.Voice interface.
+func (ps PluckedString) IsActive() bool {
+	return ps.gate || ps.level > silenceLevel
+}
+
+// GetLevel returns the peak level of the recent samples. Implements the
+// synth.Voice interface.
+func (ps PluckedString) GetLevel() float64 {
+	return ps.level
+}
+
+// Clone returns an independent copy of the string with the same settings.
+func (ps *PluckedString) Clone() Generator {
+	psTmp := *ps
+	psTmp.Noise = ps.Noise.Clone().(*NoiseGenerator)
+	psTmp.delayLine = append([]float64(nil), ps.delayLine...)
+	return &psTmp
+}
+
+// Reset re-initializes the noise generator with its seed and plucks the
+// string again. It rings with the Decay until NoteOff is called.
+func (ps *PluckedString) Reset() {
+	ps.released = false
+	ps.Noise.Reset()
+	ps.pluck()
+}
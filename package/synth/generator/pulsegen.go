@@ -0,0 +1,139 @@
+package generator
+
+type PulseGenerator struct {
+	Frequency     float64
+	DutyCycle     float64   // [0-1] the ratio of the high part of a cycle
+	PulseWidthMod Generator // modulates DutyCycle, can be nil
+	BandLimited   bool      // use PolyBLEP to reduce aliasing
+
+	sampleRate   uint
+	angleStep    float64
+	currentAngle float64
+
+	syncSource SyncSource // the master for hard sync, can be nil
+	wrapped    bool       // the last step completed or restarted a cycle
+	wrapFrac   float64    // the fraction of the last step after the wrap
+}
+
+// NewPulseGenerator creates a new pulse generator that implements the
+// Generator interface. The duty cycle defaults to 0.5 which is a square
+// wave. It can be modulated with PulseWidthMod, e.g. with an LFO or an
+// envelope for pulse width modulation.
+// The sample rate is in Hz and can't be changed later.
+func NewPulseGenerator(sampleRate uint) *PulseGenerator {
+
+	pgTmp := &PulseGenerator{
+		DutyCycle:  0.5,
+		sampleRate: sampleRate,
+		angleStep:  Tau / float64(sampleRate),
+	}
+
+	return pgTmp
+}
+
+// GetSampleRate returns the sample rate with which the generator
+// was created.
+func (pg PulseGenerator) GetSampleRate() uint {
+	return pg.sampleRate
+}
+
+// getDutyCycle returns the duty cycle with the modulation applied, clamped
+// to [0-1]. It advances the modulator, so only call it once per sample.
+func (pg *PulseGenerator) getDutyCycle() float64 {
+
+	duty := pg.DutyCycle
+	if pg.PulseWidthMod != nil {
+		duty += pg.PulseWidthMod.GetNextSample()
+	}
+
+	if duty < 0 {
+		duty = 0
+	} else if duty > 1 {
+		duty = 1
+	}
+
+	return duty
+}
+
+// GetNextSample advances the angle and returns the next sample from the
+// pulse generator.
+func (pg *PulseGenerator) GetNextSample() float64 {
+
+	step := pg.angleStep * pg.Frequency
+	duty := pg.getDutyCycle()
+
+	var sample float64
+	if pg.BandLimited {
+		sample = BLPulseFunction(pg.currentAngle/Tau, duty, step/Tau)
+	} else {
+		sample = -1
+		if pg.currentAngle < duty*Tau {
+			sample = 1
+		}
+	}
+
+	pg.currentAngle += step
+	pg.wrapped = false
+	if pg.currentAngle >= Tau {
+		pg.currentAngle -= Tau
+		pg.wrapped = true
+		pg.wrapFrac = 0
+		if step > 0 {
+			pg.wrapFrac = pg.currentAngle / step
+		}
+	}
+
+	if pg.syncSource != nil {
+		pg.syncSource.GetNextSample()
+		if wrapped, frac := pg.syncSource.CycleWrapped(); wrapped {
+			pg.currentAngle = frac * step
+
+			// the restart is a wrap for the generators synced to this one
+			pg.wrapped = true
+			pg.wrapFrac = frac
+		}
+	}
+
+	return sample
+}
+
+// CycleWrapped returns true if the last GetNextSample call completed a
+// cycle or restarted it by the sync, with the fraction [0-1) of the sample
+// step after the wrap. Implements the SyncSource interface.
+func (pg PulseGenerator) CycleWrapped() (bool, float64) {
+	return pg.wrapped, pg.wrapFrac
+}
+
+// SetSyncSource hard syncs the generator to a master, which restarts the
+// cycle whenever the master completes one. The generator advances the
+// master on every sample, so don't use the master anywhere else. The
+// restart is not band-limited, so it aliases at high frequencies.
+// Use nil to turn off the sync.
+func (pg *PulseGenerator) SetSyncSource(master SyncSource) {
+	pg.syncSource = master
+}
+
+// Clone returns an independent copy of the generator with the same settings.
+// The modulator and the sync source are cloned if they implement the Cloner
+// interface, otherwise they are shared with the copy, and advanced by both.
+func (pg *PulseGenerator) Clone() Generator {
+	pgTmp := *pg
+	pgTmp.syncSource = cloneSyncSource(pg.syncSource)
+	if cloner, ok := pg.PulseWidthMod.(Cloner); ok {
+		pgTmp.PulseWidthMod = cloner.Clone()
+	}
+	return &pgTmp
+}
+
+// Reset sets the generator back to it's starting state, and resets the
+// sync source.
+func (pg *PulseGenerator) Reset() {
+	pg.currentAngle = 0
+	pg.wrapped = false
+	if pg.syncSource != nil {
+		pg.syncSource.Reset()
+	}
+	if pg.PulseWidthMod != nil {
+		pg.PulseWidthMod.Reset()
+	}
+}
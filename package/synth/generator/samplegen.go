@@ -0,0 +1,201 @@
+package generator
+
+type LoopMode int
+
+const (
+	LoopOff      LoopMode = iota
+	LoopForward           // jumps back to the loop start at the loop end
+	LoopPingPong          // changes direction at the loop points
+)
+
+type SampleGenerator struct {
+	Frequency     float64 // the frequency to play at in Hz
+	RootFrequency float64 // the frequency of the recorded sound in Hz
+	LoopMode      LoopMode
+
+	sampleRate   uint
+	sourceRate   uint
+	samples      []float64
+	loopStart    uint
+	loopEnd      uint
+	position     float64
+	direction    float64
+	released     bool
+	finished     bool
+	sampleLength float64
+}
+
+// NewSampleGenerator creates a new sample playback generator that
+// implements the Generator interface. It plays back the samples set with
+// SetSamples and repitches them based on the ratio of the Frequency and the
+// RootFrequency, using cubic interpolation. A loop is played while the note
+// is held, TriggerRelease continues to the end of the samples. Both
+// frequencies default to 440Hz (A4) so it plays at the original pitch.
+// The sample rate is in Hz and can't be changed later.
+func NewSampleGenerator(sampleRate uint) *SampleGenerator {
+
+	sgTmp := &SampleGenerator{
+		Frequency:     440,
+		RootFrequency: 440,
+		LoopMode:      LoopOff,
+		sampleRate:    sampleRate,
+		sourceRate:    sampleRate,
+		samples:       make([]float64, 0),
+		direction:     1,
+	}
+
+	return sgTmp
+}
+
+// GetSampleRate returns the sample rate with which the generator
+// was created.
+func (sg SampleGenerator) GetSampleRate() uint {
+	return sg.sampleRate
+}
+
+// SetSamples sets the sound to play with the sample rate it was recorded
+// at, in Hz. The loop is set to the whole sound. The samples aren't copied,
+// so don't modify them afterwards.
+func (sg *SampleGenerator) SetSamples(samples []float64, sourceRate uint) {
+	sg.samples = samples
+	sg.sourceRate = sourceRate
+	sg.sampleLength = float64(len(samples))
+	sg.loopStart = 0
+	sg.loopEnd = uint(len(samples))
+	sg.Reset()
+}
+
+// SetLoopPoints sets the loop start and end positions in samples of the
+// sound. The end is exclusive and it is clamped to the sound length.
+func (sg *SampleGenerator) SetLoopPoints(startS, endS uint) {
+
+	if endS > uint(len(sg.samples)) {
+		endS = uint(len(sg.samples))
+	}
+	if startS >= endS {
+		startS = 0
+	}
+
+	sg.loopStart = startS
+	sg.loopEnd = endS
+}
+
+// TriggerRelease leaves the loop and plays the rest of the sound.
+// Useful for triggering on MIDI key release.
+func (sg *SampleGenerator) TriggerRelease() {
+	sg.released = true
+	sg.direction = 1
+}
+
+// IsFinished returns true when the end of the sound is reached.
+func (sg SampleGenerator) IsFinished() bool {
+	return sg.finished
+}
+
+// isLooping returns true while the loop is played.
+func (sg SampleGenerator) isLooping() bool {
+	return !sg.released && sg.LoopMode != LoopOff && sg.loopEnd > sg.loopStart
+}
+
+// getSampleAt returns the sample at the index, or 0 outside of the sound.
+// In a forward loop the indexes from the loop end wrap to the loop start,
+// so the interpolation is continuous over the seam.
+func (sg SampleGenerator) getSampleAt(idx int) float64 {
+	if sg.LoopMode == LoopForward && sg.isLooping() && idx >= int(sg.loopEnd) {
+		idx -= int(sg.loopEnd - sg.loopStart)
+	}
+	if idx < 0 || idx >= len(sg.samples) {
+		return 0
+	}
+	return sg.samples[idx]
+}
+
+// interpolate returns the value at the position using 4-point cubic
+// Hermite interpolation.
+func (sg SampleGenerator) interpolate(pos float64) float64 {
+
+	idx := int(pos)
+	frac := pos - float64(idx)
+
+	y0 := sg.getSampleAt(idx - 1)
+	y1 := sg.getSampleAt(idx)
+	y2 := sg.getSampleAt(idx + 1)
+	y3 := sg.getSampleAt(idx + 2)
+
+	c1 := 0.5 * (y2 - y0)
+	c2 := y0 - 2.5*y1 + 2*y2 - 0.5*y3
+	c3 := 0.5*(y3-y0) + 1.5*(y1-y2)
+
+	return ((c3*frac+c2)*frac+c1)*frac + y1
+}
+
+// GetNextSample advances the position and returns the next sample of the
+// sound, or 0 when finished.
+func (sg *SampleGenerator) GetNextSample() float64 {
+
+	if sg.finished || len(sg.samples) == 0 || sg.RootFrequency <= 0 {
+		return 0
+	}
+
+	sample := sg.interpolate(sg.position)
+
+	step := sg.Frequency / sg.RootFrequency * float64(sg.sourceRate) / float64(sg.sampleRate)
+	sg.position += step * sg.direction
+
+	looping := sg.isLooping()
+	loopStart := float64(sg.loopStart)
+	loopEnd := float64(sg.loopEnd)
+
+	if looping {
+		switch sg.LoopMode {
+
+		case LoopForward:
+			for sg.position >= loopEnd {
+				sg.position -= loopEnd - loopStart
+			}
+
+		case LoopPingPong:
+			// the turning points are the loop start and the last looped sample,
+			// a step longer than the loop turns multiple times
+			loopLast := loopEnd - 1
+			if loopLast <= loopStart {
+				if sg.position >= loopStart {
+					sg.position = loopStart
+				}
+				break
+			}
+			for {
+				if sg.position >= loopLast && sg.direction > 0 {
+					sg.position = 2*loopLast - sg.position
+					sg.direction = -1
+				} else if sg.position < loopStart && sg.direction < 0 {
+					sg.position = 2*loopStart - sg.position
+					sg.direction = 1
+				} else {
+					break
+				}
+			}
+		}
+	}
+
+	if sg.position >= sg.sampleLength || sg.position < 0 {
+		sg.finished = true
+	}
+
+	return sample
+}
+
+// Clone returns an independent copy of the generator with the same settings.
+// The samples are shared.
+func (sg *SampleGenerator) Clone() Generator {
+	sgTmp := *sg
+	return &sgTmp
+}
+
+// Reset sets the generator back to the start of the sound.
+func (sg *SampleGenerator) Reset() {
+	sg.position = 0
+	sg.direction = 1
+	sg.released = false
+	sg.finished = false
+}
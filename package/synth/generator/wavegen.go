@@ -5,13 +5,19 @@ import (
 )
 
 type FunctionGenerator struct {
-	Frequency float64
+	Frequency   float64
+	PhaseOffset float64 // added to the angle in radians, used for phase modulation
 
 	sampleRate      uint
 	angleStep       float64
 	currentAngle    float64
 	phaseShiftAngle float64
 	waveFunc        WaveFunction
+	blWaveFunc      BandLimitedWaveFunction // used instead of waveFunc if set
+
+	syncSource SyncSource // the master for hard sync, can be nil
+	wrapped    bool       // the last step completed or restarted a cycle
+	wrapFrac   float64    // the fraction of the last step after the wrap
 }
 
 // NewFunctionGenerator creates a new function generator that implements the
@@ -54,23 +60,116 @@ func (fg *FunctionGenerator) ShiftPhase(angleDeg float64) {
 // SetFunction sets the actual generator function to use.
 func (fg *FunctionGenerator) SetFunction(wf WaveFunction) {
 	fg.waveFunc = wf
+	fg.blWaveFunc = nil
+}
+
+// SetBandLimitedFunction sets a band-limited generator function to use.
+// These reduce the aliasing of high notes at the cost of some calculation.
+// Use SetFunction to switch back to a simple function.
+func (fg *FunctionGenerator) SetBandLimitedFunction(blwf BandLimitedWaveFunction) {
+	fg.blWaveFunc = blwf
 }
 
 // GetNextSample advances the angle and returns the next sample from the
 // function generator.
 func (fg *FunctionGenerator) GetNextSample() float64 {
 
-	sample := fg.waveFunc(fg.currentAngle)
+	step := fg.angleStep * fg.Frequency
+
+	angle := fg.currentAngle
+	if fg.PhaseOffset != 0 {
+		angle = math.Mod(angle+fg.PhaseOffset, Tau)
+		if angle < 0 {
+			angle += Tau
+		}
+	}
+
+	var sample float64
+	if fg.blWaveFunc != nil {
+		sample = fg.blWaveFunc(angle, step)
+	} else {
+		sample = fg.waveFunc(angle)
+	}
+
+	fg.advance(step)
+
+	if fg.syncSource != nil {
+		fg.syncSource.GetNextSample()
+		if wrapped, frac := fg.syncSource.CycleWrapped(); wrapped {
+			// restart the cycle, keeping the part of the step after the master wrapped
+			fg.currentAngle = fg.phaseShiftAngle + frac*step
+			if fg.currentAngle >= Tau {
+				fg.currentAngle -= Tau
+			}
+
+			// the restart is a wrap for the generators synced to this one
+			fg.wrapped = true
+			fg.wrapFrac = frac
+		}
+	}
+
+	return sample
+}
+
+// advance steps the angle and keeps track of the cycle wraps.
+func (fg *FunctionGenerator) advance(step float64) {
+
+	fg.currentAngle += step
+	fg.wrapped = false
 
-	fg.currentAngle += fg.angleStep * fg.Frequency
 	if fg.currentAngle >= Tau {
 		fg.currentAngle -= Tau
+		fg.wrapped = true
+		fg.wrapFrac = 0
+		if step > 0 {
+			fg.wrapFrac = fg.currentAngle / step
+		}
 	}
+}
 
-	return sample
+// CycleWrapped returns true if the last GetNextSample call completed a
+// cycle or restarted it by the sync, with the fraction [0-1) of the sample
+// step after the wrap. Implements the SyncSource interface.
+func (fg FunctionGenerator) CycleWrapped() (bool, float64) {
+	return fg.wrapped, fg.wrapFrac
+}
+
+// GetPhase returns the position [0-1) in the current cycle.
+func (fg FunctionGenerator) GetPhase() float64 {
+	return fg.currentAngle / Tau
+}
+
+// SetSyncSource hard syncs the generator to a master, which restarts the
+// cycle whenever the master completes one. The generator advances the
+// master on every sample, so don't use the master anywhere else. The
+// restart is not band-limited, even with a band-limited function, so it
+// aliases at high frequencies.
+// Use nil to turn off the sync.
+func (fg *FunctionGenerator) SetSyncSource(master SyncSource) {
+	fg.syncSource = master
+}
+
+// Clone returns an independent copy of the generator with the same settings.
+// The sync source is cloned if it implements the Cloner interface,
+// otherwise it is shared with the copy, and advanced by both.
+// Implements the Cloner interface.
+func (fg *FunctionGenerator) Clone() Generator {
+	fgTmp := *fg
+	fgTmp.syncSource = cloneSyncSource(fg.syncSource)
+	return &fgTmp
 }
 
-// Reset sets the generator back to it's starting state.
+// GetSyncSource returns the master of the hard sync, or nil.
+func (fg FunctionGenerator) GetSyncSource() SyncSource {
+	return fg.syncSource
+}
+
+// Reset sets the generator back to it's starting state, and resets the
+// sync source.
 func (fg *FunctionGenerator) Reset() {
 	fg.currentAngle = fg.phaseShiftAngle
+	fg.wrapped = false
+	if fg.syncSource != nil {
+		fg.syncSource.Reset()
+	}
 }
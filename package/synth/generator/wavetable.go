@@ -0,0 +1,198 @@
+package generator
+
+import "math"
+
+// DefaultTableSize is the number of samples in a single-cycle table when
+// built from a function or harmonics.
+const DefaultTableSize = 2048
+
+type WavetableGenerator struct {
+	Frequency   float64
+	Position    float64   // [0-1] morphs from the first table to the last
+	PositionMod Generator // modulates Position, can be nil
+
+	sampleRate   uint
+	phaseStep    float64
+	currentPhase float64 // [0-1)
+	tables       [][]float64
+}
+
+// NewWavetableGenerator creates a new wavetable generator that implements
+// the Generator interface. It plays single-cycle tables added with the
+// AddTable functions, interpolating within a table and morphing between
+// the neighbouring tables based on the position. It outputs 0 until a
+// table is added.
+// The sample rate is in Hz and can't be changed later.
+func NewWavetableGenerator(sampleRate uint) *WavetableGenerator {
+
+	wgTmp := &WavetableGenerator{
+		sampleRate: sampleRate,
+		phaseStep:  1 / float64(sampleRate),
+		tables:     make([][]float64, 0),
+	}
+
+	return wgTmp
+}
+
+// GetSampleRate returns the sample rate with which the generator
+// was created.
+func (wg WavetableGenerator) GetSampleRate() uint {
+	return wg.sampleRate
+}
+
+// AddTable adds a single-cycle table. The tables can have different sizes.
+// The table isn't copied, so don't modify it afterwards.
+func (wg *WavetableGenerator) AddTable(table []float64) {
+	if len(table) == 0 {
+		return
+	}
+	wg.tables = append(wg.tables, table)
+}
+
+// AddTableFromFunction adds a table of the given size sampled from a wave
+// function over a full cycle.
+func (wg *WavetableGenerator) AddTableFromFunction(wf WaveFunction, size int) {
+
+	table := make([]float64, size)
+	for sampleIdx := range table {
+		table[sampleIdx] = wf(Tau * float64(sampleIdx) / float64(size))
+	}
+
+	wg.AddTable(table)
+}
+
+// AddTableFromHarmonics adds a table of the given size built from sine
+// partials. The amplitudes are for the harmonics starting with the
+// fundamental, and the result is normalized to [-1-1].
+func (wg *WavetableGenerator) AddTableFromHarmonics(amplitudes []float64, size int) {
+
+	table := make([]float64, size)
+	for harmonicIdx, amplitude := range amplitudes {
+		if amplitude == 0 {
+			continue
+		}
+		for sampleIdx := range table {
+			angle := Tau * float64(harmonicIdx+1) * float64(sampleIdx) / float64(size)
+			table[sampleIdx] += amplitude * math.Sin(angle)
+		}
+	}
+
+	normalizeTable(table)
+
+	wg.AddTable(table)
+}
+
+// AddTablesFromSamples splits the samples into tables of tableSize samples
+// each, and adds them in order. This is the usual layout of wavetable WAV
+// files. A partial table at the end is dropped.
+func (wg *WavetableGenerator) AddTablesFromSamples(samples []float64, tableSize int) {
+
+	if tableSize <= 0 {
+		return
+	}
+
+	for start := 0; start+tableSize <= len(samples); start += tableSize {
+		table := make([]float64, tableSize)
+		copy(table, samples[start:start+tableSize])
+		wg.AddTable(table)
+	}
+}
+
+// ClearTables simply empties the table slice.
+func (wg *WavetableGenerator) ClearTables() {
+	wg.tables = make([][]float64, 0)
+}
+
+// GetTableCount returns the number of tables.
+func (wg WavetableGenerator) GetTableCount() int {
+	return len(wg.tables)
+}
+
+func normalizeTable(table []float64) {
+
+	var peak float64
+	for _, value := range table {
+		peak = math.Max(peak, math.Abs(value))
+	}
+	if peak == 0 {
+		return
+	}
+
+	for sampleIdx := range table {
+		table[sampleIdx] /= peak
+	}
+}
+
+// readTable returns the linearly interpolated value of the table at the
+// phase [0-1).
+func readTable(table []float64, phase float64) float64 {
+
+	pos := phase * float64(len(table))
+	idx := int(pos)
+	frac := pos - float64(idx)
+
+	if idx >= len(table) {
+		idx = len(table) - 1
+	}
+	nextIdx := idx + 1
+	if nextIdx >= len(table) {
+		nextIdx = 0
+	}
+
+	return table[idx] + (table[nextIdx]-table[idx])*frac
+}
+
+// GetNextSample advances the phase and returns the next sample morphed
+// between the tables at the current position.
+func (wg *WavetableGenerator) GetNextSample() float64 {
+
+	position := wg.Position
+	if wg.PositionMod != nil {
+		position += wg.PositionMod.GetNextSample()
+	}
+
+	var sample float64
+	if len(wg.tables) > 0 {
+
+		if position < 0 {
+			position = 0
+		} else if position > 1 {
+			position = 1
+		}
+
+		tablePos := position * float64(len(wg.tables)-1)
+		tableIdx := int(tablePos)
+		frac := tablePos - float64(tableIdx)
+
+		sample = readTable(wg.tables[tableIdx], wg.currentPhase)
+		if frac > 0 && tableIdx+1 < len(wg.tables) {
+			nextSample := readTable(wg.tables[tableIdx+1], wg.currentPhase)
+			sample += (nextSample - sample) * frac
+		}
+	}
+
+	wg.currentPhase += wg.phaseStep * wg.Frequency
+	wg.currentPhase -= math.Floor(wg.currentPhase)
+
+	return sample
+}
+
+// Clone returns an independent copy of the generator with the same settings.
+// The tables are shared. The modulator is cloned if it implements the
+// Cloner interface, otherwise it is shared with the copy.
+func (wg *WavetableGenerator) Clone() Generator {
+	wgTmp := *wg
+	wgTmp.tables = append([][]float64(nil), wg.tables...)
+	if cloner, ok := wg.PositionMod.(Cloner); ok {
+		wgTmp.PositionMod = cloner.Clone()
+	}
+	return &wgTmp
+}
+
+// Reset sets the generator back to it's starting state.
+func (wg *WavetableGenerator) Reset() {
+	wg.currentPhase = 0
+	if wg.PositionMod != nil {
+		wg.PositionMod.Reset()
+	}
+}
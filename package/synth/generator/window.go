@@ -0,0 +1,41 @@
+package generator
+
+import (
+	"math"
+)
+
+// GetHannWindow returns a Hann window of the size, which fades in and out
+// to 0 with a raised cosine.
+func GetHannWindow(size int) []float64 {
+
+	window := make([]float64, size)
+	for i := 0; i < size; i++ {
+		window[i] = 0.5 * (1 - math.Cos(2*math.Pi*float64(i)/float64(size-1)))
+	}
+
+	return window
+}
+
+// GetHammingWindow returns a Hamming window of the size, which is like the
+// Hann window, but doesn't fade fully to 0 at the ends.
+func GetHammingWindow(size int) []float64 {
+
+	window := make([]float64, size)
+	for i := 0; i < size; i++ {
+		window[i] = 0.54 - 0.46*math.Cos(2*math.Pi*float64(i)/float64(size-1))
+	}
+
+	return window
+}
+
+// GetBlackmanWindow returns a Blackman window of the size, which is
+// narrower than the Hann window with softer ends.
+func GetBlackmanWindow(size int) []float64 {
+
+	window := make([]float64, size)
+	for i := 0; i < size; i++ {
+		window[i] = 0.42 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(size-1)) + 0.08*math.Cos(4*math.Pi*float64(i)/float64(size-1))
+	}
+
+	return window
+}
@@ -0,0 +1,101 @@
+package synth
+
+import (
+	"math"
+
+	"github.com/rawbits2010/LibBitDauer/package/synth/buffer"
+)
+
+type GlideMode uint
+
+const (
+	GlideOff    GlideMode = iota // the pitch jumps to the new frequency
+	GlideAlways                  // every frequency change glides
+	GlideLegato                  // only notes played while another is held glide
+)
+
+type GlideCurve uint
+
+const (
+	GlidePitch  GlideCurve = iota // linear in cents, even speed on every octave
+	GlideLinear                   // linear in Hz, slower on low notes
+)
+
+// SetGlideTime sets how long it takes to reach a new frequency in
+// milliseconds, no matter how far it is.
+func (osc *Oscillator) SetGlideTime(glideMS uint) {
+	osc.glideS = float64(buffer.CalcSampleLength(osc.sampleRate, glideMS))
+	osc.glideByRate = false
+}
+
+// SetGlideRate sets how long it takes to glide an octave in milliseconds,
+// so the glide time depends on the distance of the frequencies.
+func (osc *Oscillator) SetGlideRate(octaveMS uint) {
+	osc.glideS = float64(buffer.CalcSampleLength(osc.sampleRate, octaveMS))
+	osc.glideByRate = true
+}
+
+// startGlide starts a glide from the current frequency to the Frequency,
+// or jumps there if the glide is off.
+func (osc *Oscillator) startGlide() {
+
+	osc.glideTarget = osc.Frequency
+
+	if osc.GlideMode == GlideOff || osc.glideCurrent <= 0 || osc.Frequency <= 0 {
+		osc.jumpGlide()
+		return
+	}
+
+	osc.glideFrom = osc.glideCurrent
+	osc.currGlideS = 0
+
+	lengthS := osc.glideS
+	if osc.glideByRate {
+		lengthS *= math.Abs(math.Log2(osc.glideTarget / osc.glideFrom))
+	}
+	osc.glideLengthS = uint(math.Round(lengthS))
+}
+
+// jumpGlide ends the glide at the Frequency.
+func (osc *Oscillator) jumpGlide() {
+	osc.glideTarget = osc.Frequency
+	osc.glideFrom = osc.Frequency
+	osc.glideCurrent = osc.Frequency
+	osc.glideLengthS = 0
+	osc.currGlideS = 0
+}
+
+// getGlideFrequency returns the frequency in Hz on the way to Frequency,
+// and advances the glide. A changed Frequency starts a new glide from the
+// current one.
+func (osc *Oscillator) getGlideFrequency() float64 {
+
+	if osc.Frequency != osc.glideTarget {
+		osc.startGlide()
+	}
+
+	if osc.currGlideS >= osc.glideLengthS {
+		osc.glideCurrent = osc.glideTarget
+		return osc.glideCurrent
+	}
+
+	osc.currGlideS++
+	pos := osc.GlideEasing.GetValue(0, osc.glideLengthS, osc.currGlideS)
+
+	switch osc.GlideCurve {
+
+	case GlideLinear:
+		osc.glideCurrent = osc.glideFrom + (osc.glideTarget-osc.glideFrom)*pos
+
+	default:
+		osc.glideCurrent = osc.glideFrom * math.Pow(osc.glideTarget/osc.glideFrom, pos)
+	}
+
+	return osc.glideCurrent
+}
+
+// GetCurrentFrequency returns the frequency in Hz of the last sample with
+// the glide applied, but without the FrequencyMod.
+func (osc Oscillator) GetCurrentFrequency() float64 {
+	return osc.glideCurrent
+}
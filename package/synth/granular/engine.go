@@ -0,0 +1,219 @@
+package granular
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/rawbits2010/LibBitDauer/package/synth/buffer"
+	"github.com/rawbits2010/LibBitDauer/package/synth/generator"
+)
+
+type WindowShape int
+
+const (
+	HannWindow WindowShape = iota
+	HammingWindow
+	BlackmanWindow
+)
+
+// minGrainS is the shortest grain in samples, the windows need at least 2.
+const minGrainS = 2
+
+type grain struct {
+	position float64 // in source samples
+	age      int     // in output samples
+}
+
+type Engine struct {
+	Density        float64 // grains started per second
+	Position       float64 // [0-1] grain start position in the source
+	PositionJitter float64 // [0-1] random range added to the position
+	Pitch          float64 // playback speed of the grains, 1 is the original
+	Window         WindowShape
+	MaxGrains      int // the oldest grain is dropped above this
+
+	sampleRate uint
+	source     []float64
+	sourceRate uint
+
+	grainS      uint
+	window      []float64
+	windowShape WindowShape
+
+	rndSeed    int64
+	rndFunc    *rand.Rand
+	grains     []grain
+	nextGrainS float64 // samples until the next grain starts
+}
+
+// NewEngine creates a granular synthesis engine that implements the
+// Generator interface. It plays overlapping windowed grains from the source
+// set with SetSource. The grains start at fixed intervals by the Density,
+// only the position jitter is random, using a random generator seeded with
+// SetSeed, so the renders are reproducible.
+// The output is scaled down by the square root of the grain overlap.
+// The sample rate is in Hz and can't be changed later.
+func NewEngine(sampleRate uint) *Engine {
+
+	engTmp := &Engine{
+		Density:    20,
+		Pitch:      1,
+		Window:     HannWindow,
+		MaxGrains:  64,
+		sampleRate: sampleRate,
+		source:     make([]float64, 0),
+		sourceRate: sampleRate,
+		grains:     make([]grain, 0),
+	}
+	engTmp.SetGrainSize(100)
+	engTmp.SetSeed(0)
+
+	return engTmp
+}
+
+// GetSampleRate returns the sample rate with which the engine
+// was created.
+func (eng Engine) GetSampleRate() uint {
+	return eng.sampleRate
+}
+
+// SetSource sets the sound to take the grains from, with the sample rate
+// it was made at, in Hz. It can come from buffer.Generate or
+// record.ReadFromWav. The samples aren't copied, so don't modify them
+// afterwards.
+func (eng *Engine) SetSource(samples []float64, sourceRate uint) {
+	eng.source = samples
+	eng.sourceRate = sourceRate
+}
+
+// SetGrainSize sets the length of the grains in milliseconds. The grains
+// are at least 2 samples long, so the window can be calculated.
+func (eng *Engine) SetGrainSize(grainMS uint) {
+	eng.grainS = max(buffer.CalcSampleLength(eng.sampleRate, grainMS), minGrainS)
+	eng.window = nil
+}
+
+// SetSeed sets the seed for the random generator for consistency.
+func (eng *Engine) SetSeed(seed int64) {
+	eng.rndSeed = seed
+	eng.rndFunc = rand.New(rand.NewSource(seed))
+}
+
+// getWindow returns the window for the grain size, generating it when the
+// size or the shape changed.
+func (eng *Engine) getWindow() []float64 {
+
+	if eng.window != nil && eng.windowShape == eng.Window {
+		return eng.window
+	}
+
+	switch eng.Window {
+	case HammingWindow:
+		eng.window = generator.GetHammingWindow(int(eng.grainS))
+	case BlackmanWindow:
+		eng.window = generator.GetBlackmanWindow(int(eng.grainS))
+	default:
+		eng.window = generator.GetHannWindow(int(eng.grainS))
+	}
+	eng.windowShape = eng.Window
+
+	return eng.window
+}
+
+// startGrain adds a new grain at the jittered position.
+func (eng *Engine) startGrain() {
+
+	position := eng.Position + (eng.rndFunc.Float64()*2-1)*eng.PositionJitter
+	position = math.Min(math.Max(position, 0), 1)
+
+	if len(eng.grains) >= eng.MaxGrains && len(eng.grains) > 0 {
+		eng.grains = eng.grains[1:]
+	}
+
+	eng.grains = append(eng.grains, grain{
+		position: position * float64(len(eng.source)-1),
+	})
+}
+
+// getSourceAt returns the linearly interpolated source value at the
+// position, or 0 outside of the source.
+func (eng Engine) getSourceAt(pos float64) float64 {
+
+	if pos < 0 {
+		return 0
+	}
+
+	idx := int(pos)
+	if idx+1 >= len(eng.source) {
+		if idx < len(eng.source) {
+			return eng.source[idx]
+		}
+		return 0
+	}
+
+	frac := pos - float64(idx)
+	return eng.source[idx] + (eng.source[idx+1]-eng.source[idx])*frac
+}
+
+// GetNextSample starts new grains based on the density, and returns the
+// sum of the playing grains.
+func (eng *Engine) GetNextSample() float64 {
+
+	if len(eng.source) == 0 || eng.grainS == 0 {
+		return 0
+	}
+
+	if eng.Density > 0 {
+		for eng.nextGrainS <= 0 {
+			eng.startGrain()
+			eng.nextGrainS += float64(eng.sampleRate) / eng.Density
+		}
+		eng.nextGrainS--
+	}
+
+	window := eng.getWindow()
+	step := eng.Pitch * float64(eng.sourceRate) / float64(eng.sampleRate)
+
+	var sample float64
+	alive := eng.grains[:0]
+	for _, gr := range eng.grains {
+
+		// the grain size might have changed since the grain started
+		if gr.age >= len(window) {
+			continue
+		}
+
+		sample += eng.getSourceAt(gr.position) * window[gr.age]
+
+		gr.position += step
+		gr.age++
+		if gr.age < len(window) {
+			alive = append(alive, gr)
+		}
+	}
+	eng.grains = alive
+
+	overlap := eng.Density * float64(eng.grainS) / float64(eng.sampleRate)
+	if overlap > 1 {
+		sample /= math.Sqrt(overlap)
+	}
+
+	return sample
+}
+
+// Clone returns an independent copy of the engine with the same settings.
+// The source is shared.
+func (eng *Engine) Clone() *Engine {
+	engTmp := *eng
+	engTmp.grains = append([]grain(nil), eng.grains...)
+	engTmp.SetSeed(eng.rndSeed)
+	return &engTmp
+}
+
+// Reset stops all the grains and re-initializes the random generator with
+// the set seed.
+func (eng *Engine) Reset() {
+	eng.grains = eng.grains[:0]
+	eng.nextGrainS = 0
+	eng.SetSeed(eng.rndSeed)
+}
@@ -0,0 +1,99 @@
+package mixer
+
+import (
+	"github.com/rawbits2010/LibBitDauer/package/synth/filter"
+	"github.com/rawbits2010/LibBitDauer/package/synth/generator"
+)
+
+type Bus struct {
+	Gain float64
+
+	sampleRate uint
+	channels   []*Channel
+	inserts    filter.FilterChain
+}
+
+// NewBus creates a mixer bus that sums its channels and implements the
+// Generator interface. The top level bus is the master bus. Sub-groups are
+// made by adding a bus as the input of a channel in another bus, so the
+// channel gain, mute and solo works for the whole group.
+// The sample rate is in Hz and can't be changed later. It should match the
+// sample rate of all the inputs.
+func NewBus(sampleRate uint) *Bus {
+	return &Bus{
+		Gain:       1,
+		sampleRate: sampleRate,
+		channels:   make([]*Channel, 0),
+		inserts:    *filter.NewFilterChain(),
+	}
+}
+
+// GetSampleRate returns the sample rate with which the bus was created.
+func (bus Bus) GetSampleRate() uint {
+	return bus.sampleRate
+}
+
+// AddChannel adds a channel with the input to the bus and returns it so the
+// gain, mute and solo can be set.
+func (bus *Bus) AddChannel(input generator.Generator) *Channel {
+	ch := NewChannel(input)
+	bus.channels = append(bus.channels, ch)
+	return ch
+}
+
+// GetChannels returns the channels of the bus in the order they were added.
+func (bus Bus) GetChannels() []*Channel {
+	return bus.channels
+}
+
+// ClearChannels simply empties the channel slice.
+func (bus *Bus) ClearChannels() {
+	bus.channels = make([]*Channel, 0)
+}
+
+// AddInsert adds a filter to the insert chain. The inserts process the sum
+// of the channels before the bus gain, in the order of these calls.
+func (bus *Bus) AddInsert(insert filter.Filter) {
+	bus.inserts.AddFilter(insert)
+}
+
+// ClearInserts simply empties the insert chain.
+func (bus *Bus) ClearInserts() {
+	bus.inserts.ClearFilters()
+}
+
+// GetNextSample returns the sum of the audible channels run through the
+// insert chain and multiplied by the bus gain. Every input is advanced even
+// when muted, so they stay in sync.
+func (bus *Bus) GetNextSample() float64 {
+
+	soloed := false
+	for _, ch := range bus.channels {
+		if ch.Solo {
+			soloed = true
+			break
+		}
+	}
+
+	var sample float64
+	for _, ch := range bus.channels {
+		chSample := ch.GetNextSample()
+
+		if ch.Mute || (soloed && !ch.Solo) {
+			continue
+		}
+		sample += chSample
+	}
+
+	sample = bus.inserts.Filter(sample)
+
+	return sample * bus.Gain
+}
+
+// Reset resets all the channel inputs and the inserts.
+func (bus *Bus) Reset() {
+	for _, ch := range bus.channels {
+		ch.Reset()
+	}
+	bus.inserts.Reset()
+}
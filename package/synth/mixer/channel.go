@@ -0,0 +1,36 @@
+package mixer
+
+import "github.com/rawbits2010/LibBitDauer/package/synth/generator"
+
+type Channel struct {
+	Input generator.Generator
+	Gain  float64
+	Mute  bool
+	Solo  bool // when any channel is soloed only those are heard in the bus
+}
+
+// NewChannel creates a mixer channel for any object that implements the
+// Generator interface. It starts with unity gain, unmuted. Use Bus.AddChannel
+// to add it to a bus.
+func NewChannel(input generator.Generator) *Channel {
+	return &Channel{
+		Input: input,
+		Gain:  1,
+	}
+}
+
+// GetSampleRate returns the sample rate of the input.
+func (ch Channel) GetSampleRate() uint {
+	return ch.Input.GetSampleRate()
+}
+
+// GetNextSample returns the next sample of the input multiplied by the gain.
+// Mute and solo are handled by the bus, so they are ignored here.
+func (ch *Channel) GetNextSample() float64 {
+	return ch.Input.GetNextSample() * ch.Gain
+}
+
+// Reset simply resets the input.
+func (ch *Channel) Reset() {
+	ch.Input.Reset()
+}
@@ -2,6 +2,7 @@ package modulator
 
 import (
 	"github.com/rawbits2010/LibBitDauer/package/synth/buffer"
+	"github.com/rawbits2010/LibBitDauer/package/synth/generator"
 	"github.com/rawbits2010/LibBitDauer/package/synth/modulator/easing"
 )
 
@@ -14,10 +15,11 @@ const (
 	Release
 )
 
-// TODO: need to rethink this so GetNextSample won't modify the object.
-// For example make ADSR into a separate Envelope object and pass the
-// currSample to the GetNextSample function.
-type ADSR struct {
+// ADSRShape is the description of an ADSR envelope. It is never modified
+// while the envelope is played, the progress and whether the note is held
+// are kept in an ADSRState, so one shape can be shared by any number of
+// voices.
+type ADSRShape struct {
 	attackS      uint
 	AttackCurve  easing.Easing
 	decayS       uint
@@ -27,103 +29,265 @@ type ADSR struct {
 	releaseS     uint
 	ReleaseCurve easing.Easing
 
-	sampleRate       uint
-	currPhase        ADSRPhase
-	releaseTriggered bool
-	ManualSustain    bool // infinite sustain time when true
-	currSample       uint
+	ManualSustain bool    // infinite sustain time even without a Trigger
+	VelocityLevel float64 // [0-1] how much the velocity scales the peak and the sustain level
+	VelocityTime  float64 // [0-1] how much a high velocity shortens the attack and decay
+
+	sampleRate uint
 }
 
-// NewADSR creates a new ADSR envelope. The sample rate is in Hz and can't
-// be changed afterwards.
-func NewADSR(sampleRate uint) *ADSR {
-	adsrTmp := &ADSR{
+// ADSRState is the progress of a voice through an ADSRShape.
+type ADSRState struct {
+	phase      ADSRPhase
+	currSample uint
+	level      float64 // the last value
+	startLevel float64 // the level the current phase started from
+	velocity   float64
+	gate       bool // true between Trigger and Release, holds the sustain
+}
+
+type ADSR struct {
+	ADSRShape
+	state ADSRState
+}
+
+// NewADSRShape creates a new ADSR envelope description with no attack,
+// decay and release, and a sustain level of 1. The sample rate is in Hz and
+// can't be changed afterwards.
+func NewADSRShape(sampleRate uint) ADSRShape {
+	return ADSRShape{
 		sampleRate:   sampleRate,
 		sustain:      1,
 		AttackCurve:  *easing.NewLERP(),
 		DecayCurve:   *easing.NewLERP(),
 		ReleaseCurve: *easing.NewLERP(),
 	}
+}
+
+// NewADSRState creates the state for a voice at the start of the attack
+// phase, with full velocity.
+func NewADSRState() ADSRState {
+	return ADSRState{
+		phase:    Attack,
+		velocity: 1,
+	}
+}
+
+// NewADSR creates a new ADSR envelope which holds a shape and the state of
+// a single voice. The sample rate is in Hz and can't be changed afterwards.
+func NewADSR(sampleRate uint) *ADSR {
+	adsrTmp := &ADSR{
+		ADSRShape: NewADSRShape(sampleRate),
+		state:     NewADSRState(),
+	}
 	return adsrTmp
 }
 
 // SetAttackLength sets the length of the attack phase in milliseconds.
-func (adsr *ADSR) SetAttackLength(attackMS uint) {
-	adsr.attackS = buffer.CalcSampleLength(adsr.sampleRate, attackMS)
+func (shape *ADSRShape) SetAttackLength(attackMS uint) {
+	shape.attackS = buffer.CalcSampleLength(shape.sampleRate, attackMS)
 }
 
 // SetDecayLength sets the length of the decay phase in milliseconds.
-func (adsr *ADSR) SetDecayLength(decayMS uint) {
-	adsr.decayS = buffer.CalcSampleLength(adsr.sampleRate, decayMS)
+func (shape *ADSRShape) SetDecayLength(decayMS uint) {
+	shape.decayS = buffer.CalcSampleLength(shape.sampleRate, decayMS)
 }
 
 // SetSustain sets the sustain value directly.
-func (adsr *ADSR) SetSustain(value float64) {
-	adsr.sustain = value
+func (shape *ADSRShape) SetSustain(value float64) {
+	shape.sustain = value
 }
 
 // SetSustainLength Sets the exact sustain time. Make sure you also set
 // the release time! Use this when you know the length of the sound.
-func (adsr *ADSR) SetSustainLength(sustainMS uint) {
-	adsr.sustainS = buffer.CalcSampleLength(adsr.sampleRate, sustainMS)
-}
-
-// TriggerRelease Start the release phase instatly when in sustain phase.
-// Use SetReleaseLength in this case. Useful for triggering on MIDI key release.
-func (adsr *ADSR) TriggerRelease() {
-	adsr.releaseTriggered = true
+func (shape *ADSRShape) SetSustainLength(sustainMS uint) {
+	shape.sustainS = buffer.CalcSampleLength(shape.sampleRate, sustainMS)
 }
 
 // SetReleaseLength Sets the release time. Use this if you want to trigger
 // the release specifically with TriggerRelease.
-func (adsr *ADSR) SetReleaseLength(releaseMS uint) {
-	adsr.releaseS = buffer.CalcSampleLength(adsr.sampleRate, releaseMS)
+func (shape *ADSRShape) SetReleaseLength(releaseMS uint) {
+	shape.releaseS = buffer.CalcSampleLength(shape.sampleRate, releaseMS)
 }
 
-// GetSampleRate returns the sample rate with which the envelope
-// was created.
-func (adsr ADSR) GetSampleRate() uint {
-	return adsr.sampleRate
+// GetSampleRate returns the sample rate with which the shape was created.
+func (shape ADSRShape) GetSampleRate() uint {
+	return shape.sampleRate
 }
 
-// GetNextSample returns the next envelope value based on the ADSR settings.
-func (adsr *ADSR) GetNextSample() float64 {
+// getPeak returns the level at the end of the attack phase for the
+// velocity.
+func (shape ADSRShape) getPeak(velocity float64) float64 {
+	return 1 - shape.VelocityLevel*(1-velocity)
+}
 
-	curr := adsr.currSample
-	adsr.currSample++
+// scaleLength returns the length in samples shortened by the velocity.
+func (shape ADSRShape) scaleLength(lengthS uint, velocity float64) uint {
+	if shape.VelocityTime == 0 {
+		return lengthS
+	}
+	scale := 1 - shape.VelocityTime*velocity
+	if scale < 0 {
+		scale = 0
+	}
+	return uint(float64(lengthS) * scale)
+}
+
+// Trigger starts the attack phase of the state from its current level, so
+// a retriggered note doesn't click. The sustain is held until Release, like
+// with ManualSustain. The velocity [0-1] scales the levels and times as set
+// by VelocityLevel and VelocityTime. For legato, don't trigger while the
+// note is held, like the oscillator does in Legato mode.
+func (shape ADSRShape) Trigger(state *ADSRState, velocity float64) {
+
+	state.phase = Attack
+	state.currSample = 0
+	state.startLevel = state.level
+	state.velocity = velocity
+	state.gate = true
+}
+
+// Release starts the release phase of the state from its current level,
+// from any phase.
+func (shape ADSRShape) Release(state *ADSRState) {
+
+	state.gate = false
+
+	if state.phase == Release {
+		return
+	}
+
+	state.phase = Release
+	state.currSample = 0
+	state.startLevel = state.level
+}
 
-	switch adsr.currPhase {
+// Next advances the state and returns the next envelope value.
+func (shape ADSRShape) Next(state *ADSRState) float64 {
+
+	curr := state.currSample
+	state.currSample++
+
+	peak := shape.getPeak(state.velocity)
+	sustain := shape.sustain * peak
+
+	switch state.phase {
 
 	case Attack:
-		if curr == adsr.attackS {
-			adsr.currPhase = Decay
-			adsr.currSample = 0
+		attackS := shape.scaleLength(shape.attackS, state.velocity)
+		if curr >= attackS {
+			state.phase = Decay
+			state.currSample = 0
 		}
-		return adsr.AttackCurve.GetValue(0, adsr.attackS, curr)
+		state.level = state.startLevel + (peak-state.startLevel)*shape.AttackCurve.GetValue(0, attackS, curr)
 
 	case Decay:
-		if curr == adsr.decayS {
-			adsr.currPhase = Sustain
-			adsr.currSample = 0
+		decayS := shape.scaleLength(shape.decayS, state.velocity)
+		if curr >= decayS {
+			state.phase = Sustain
+			state.currSample = 0
 		}
-		return adsr.sustain + ((1 - adsr.sustain) * (1 - adsr.DecayCurve.GetValue(0, adsr.decayS, curr)))
+		state.level = sustain + ((peak - sustain) * (1 - shape.DecayCurve.GetValue(0, decayS, curr)))
 
 	case Sustain:
-		if !adsr.releaseTriggered {
-			if !adsr.ManualSustain && curr >= adsr.sustainS {
-				adsr.currPhase = Release
-				adsr.currSample = 0
-			}
-			return adsr.sustain
+		if !shape.ManualSustain && !state.gate && curr >= shape.sustainS {
+			state.phase = Release
+			state.currSample = 0
+			state.startLevel = sustain
 		}
-		fallthrough
+		state.level = sustain
 
 	default:
-		return adsr.sustain * (1 - adsr.ReleaseCurve.GetValue(0, adsr.releaseS, curr))
+		if curr >= shape.releaseS {
+			state.level = 0
+		} else {
+			state.level = state.startLevel * (1 - shape.ReleaseCurve.GetValue(0, shape.releaseS, curr))
+		}
 	}
+
+	return state.level
+}
+
+// IsFinished returns true when the release phase of the state is over and
+// the envelope only outputs 0.
+func (shape ADSRShape) IsFinished(state ADSRState) bool {
+	return state.phase == Release && state.currSample > shape.releaseS
+}
+
+// Reset sets the state back to the start of the attack phase from 0, with
+// the note released. The velocity is kept.
+func (state *ADSRState) Reset() {
+	state.phase = Attack
+	state.currSample = 0
+	state.level = 0
+	state.startLevel = 0
+	state.gate = false
+}
+
+// GetPhase returns the current phase.
+func (state ADSRState) GetPhase() ADSRPhase {
+	return state.phase
+}
+
+// GetLevel returns the last envelope value.
+func (state ADSRState) GetLevel() float64 {
+	return state.level
+}
+
+// GetSampleRate returns the sample rate with which the envelope
+// was created.
+func (adsr ADSR) GetSampleRate() uint {
+	return adsr.sampleRate
+}
+
+// GetNextSample returns the next envelope value based on the ADSR settings.
+func (adsr *ADSR) GetNextSample() float64 {
+	return adsr.Next(&adsr.state)
+}
+
+// Trigger starts the attack phase from the current level with a velocity
+// [0-1]. See ADSRShape.Trigger for the details.
+func (adsr *ADSR) Trigger(velocity float64) {
+	adsr.ADSRShape.Trigger(&adsr.state, velocity)
+}
+
+// TriggerRelease starts the release phase instantly from the current level,
+// in any phase. Use SetReleaseLength in this case. Useful for triggering on
+// MIDI key release.
+func (adsr *ADSR) TriggerRelease() {
+	adsr.Release(&adsr.state)
+}
+
+// IsFinished returns true when the release phase is over and the envelope
+// only outputs 0.
+func (adsr ADSR) IsFinished() bool {
+	return adsr.ADSRShape.IsFinished(adsr.state)
+}
+
+// GetPhase returns the current phase of the envelope.
+func (adsr ADSR) GetPhase() ADSRPhase {
+	return adsr.state.phase
+}
+
+// GetLevel returns the last envelope value.
+func (adsr ADSR) GetLevel() float64 {
+	return adsr.state.level
+}
+
+// GetShape returns a copy of the shape, which can be used with separate
+// ADSRState values.
+func (adsr ADSR) GetShape() ADSRShape {
+	return adsr.ADSRShape
+}
+
+// Clone returns an independent copy of the envelope with the same settings.
+// Implements the generator.Cloner interface.
+func (adsr *ADSR) Clone() generator.Generator {
+	adsrTmp := *adsr
+	return &adsrTmp
 }
 
-// Reset will set the
+// Reset sets the envelope back to the start of the attack phase from 0.
 func (adsr *ADSR) Reset() {
-	adsr.releaseTriggered = false
+	adsr.state.Reset()
 }
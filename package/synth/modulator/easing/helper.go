@@ -1,7 +1,11 @@
 package easing
 
 // NormalisePosition returns the normalized [0-1] position for currPos, between startPos and endPos
+// A zero length range counts as finished.
 func NormalisePosition(startPos, endPos, currPos uint) float64 {
+	if endPos <= startPos {
+		return 1
+	}
 	return float64(currPos-startPos) / float64(endPos-startPos)
 }
 
@@ -0,0 +1,25 @@
+package modulator
+
+import "github.com/rawbits2010/LibBitDauer/package/synth/generator"
+
+// Envelope is an interface for envelopes that can be played with note-on
+// and note-off events, like the ADSR and the MultiSegmentEnvelope, so they
+// can be used in the oscillators and the voices.
+type Envelope interface {
+	generator.Generator
+	Trigger(velocity float64) // starts the envelope, holds the sustain
+	TriggerRelease()          // starts the release from the current level
+	IsFinished() bool         // the release is over
+	GetLevel() float64        // the last value, without advancing
+}
+
+// CloneEnvelope returns a copy of the envelope if it implements the
+// generator.Cloner interface, otherwise the same envelope.
+func CloneEnvelope(env Envelope) Envelope {
+	if cloner, ok := env.(generator.Cloner); ok {
+		if envTmp, ok := cloner.Clone().(Envelope); ok {
+			return envTmp
+		}
+	}
+	return env
+}
@@ -1,5 +1,7 @@
 package modulator
 
+import "github.com/rawbits2010/LibBitDauer/package/synth/generator"
+
 type FlatModulation struct {
 	sampleRate uint
 }
@@ -19,3 +21,9 @@ func (fm FlatModulation) GetNextSample() float64 {
 }
 
 func (fm *FlatModulation) Reset() {}
+
+// Clone returns a new flat modulator. Implements the generator.Cloner
+// interface.
+func (fm *FlatModulation) Clone() generator.Generator {
+	return NewFlatModulation(fm.sampleRate)
+}
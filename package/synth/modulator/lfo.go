@@ -1,18 +1,65 @@
 package modulator
 
-import "github.com/rawbits2010/LibBitDauer/package/synth/generator"
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/rawbits2010/LibBitDauer/package/synth/buffer"
+	"github.com/rawbits2010/LibBitDauer/package/synth/generator"
+)
+
+type LFOShape int
+
+const (
+	LFOWave          LFOShape = iota // the wave function of the Generator
+	LFOSampleAndHold                 // a new random value every cycle
+	LFOSmoothRandom                  // glides between random values every cycle
+)
+
+// Triggerable is an interface for modulators that follow the key presses,
+// e.g. restart on every note. Voices call Trigger instead of Reset for
+// these on NoteOn.
+type Triggerable interface {
+	generator.Generator
+	Trigger()
+}
 
 type LFO struct {
 	Generator generator.FunctionGenerator
-	Deviation float64 // a multiplyer for the sample
+	Deviation float64             // a multiplyer for the sample
+	Offset    float64             // added to the sample after the Deviation
+	Unipolar  bool                // the sample is [0-1] instead of [-1-1]
+	Retrigger bool                // Trigger restarts the cycle, otherwise it's free-running
+	RateMod   generator.Generator // modulates the Frequency of the Generator in Hz
+
+	shape LFOShape
+
+	delayS uint // no modulation after Trigger
+	fadeS  uint // the modulation fades in after the delay
+	currS  uint
+
+	rndSeed   int64
+	rndFunc   *rand.Rand
+	prevValue float64 // the random value at the start of the cycle
+	nextValue float64 // the random value at the end of the cycle
 }
 
 // NewLFO creates a new function generator that can be used as a low frequency
-// oscillator. The sample rate is in Hz and can't be changed afterwards.
+// oscillator. Besides the wave functions it can output random values that
+// change every cycle, seeded with SetSeed so the renders are reproducible.
+// The rate can be synced to a tempo with SetTempoSync. The modulation can be
+// delayed and faded in after every Trigger.
+// The sample rate is in Hz and can't be changed afterwards.
 func NewLFO(sampleRate uint) *LFO {
-	return &LFO{
+	lfoTmp := &LFO{
 		Generator: *generator.NewFunctionGenerator(sampleRate),
+		RateMod:   NewFlatModulation(0),
+		shape:     LFOWave,
 	}
+	lfoTmp.SetSeed(0)
+
+	return lfoTmp
 }
 
 // GetSampleRate returns the sample rate the function generator is set to.
@@ -20,13 +67,134 @@ func (lfo LFO) GetSampleRate() uint {
 	return lfo.Generator.GetSampleRate()
 }
 
+// SetShape selects between the wave function of the Generator and the
+// random shapes.
+func (lfo *LFO) SetShape(shape LFOShape) error {
+	if shape < LFOWave || shape > LFOSmoothRandom {
+		return fmt.Errorf("invalid LFO shape: %d", shape)
+	}
+	lfo.shape = shape
+	return nil
+}
+
+// GetShape returns the selected shape.
+func (lfo LFO) GetShape() LFOShape {
+	return lfo.shape
+}
+
+// SetTempoSync sets the frequency of the Generator so a cycle takes the
+// note division at the tempo in beats per minute. Call it again when the
+// tempo changes.
+func (lfo *LFO) SetTempoSync(bpm float64, division buffer.NoteDivision) error {
+	frequency, err := buffer.CalcDivisionFrequency(bpm, division)
+	if err != nil {
+		return err
+	}
+	lfo.Generator.Frequency = frequency
+	return nil
+}
+
+// SetDelay sets the time in milliseconds after Trigger without modulation.
+func (lfo *LFO) SetDelay(delayMS uint) {
+	lfo.delayS = buffer.CalcSampleLength(lfo.GetSampleRate(), delayMS)
+}
+
+// SetFadeIn sets the time in milliseconds it takes the modulation to reach
+// the full Deviation after the delay.
+func (lfo *LFO) SetFadeIn(fadeMS uint) {
+	lfo.fadeS = buffer.CalcSampleLength(lfo.GetSampleRate(), fadeMS)
+}
+
+// SetSeed sets the seed for the random shapes for consistency.
+func (lfo *LFO) SetSeed(seed int64) {
+	lfo.rndSeed = seed
+	lfo.rndFunc = rand.New(rand.NewSource(seed))
+	lfo.prevValue = lfo.getRandomValue()
+	lfo.nextValue = lfo.getRandomValue()
+}
+
+func (lfo *LFO) getRandomValue() float64 {
+	return lfo.rndFunc.Float64()*2 - 1
+}
+
+// Trigger restarts the delay and the fade-in, and in Retrigger mode the
+// cycle as well. Call it on every key press. Implements the Triggerable
+// interface.
+func (lfo *LFO) Trigger() {
+	lfo.currS = 0
+	if lfo.Retrigger {
+		lfo.Generator.Reset()
+	}
+}
+
+// getFade returns the amount [0-1] of the modulation after the delay and
+// fade-in, and advances the time.
+func (lfo *LFO) getFade() float64 {
+
+	curr := lfo.currS
+	if curr < lfo.delayS+lfo.fadeS {
+		lfo.currS++
+	}
+
+	if curr < lfo.delayS {
+		return 0
+	}
+	if curr-lfo.delayS >= lfo.fadeS {
+		return 1
+	}
+	return float64(curr-lfo.delayS) / float64(lfo.fadeS)
+}
+
 // GetNextSample returns the next sample from the generator multiplied by
-// the Deviation.
-func (lfo LFO) GetNextSample() float64 {
-	return lfo.Deviation * lfo.Generator.GetNextSample()
+// the Deviation and the fade-in, with the Offset added.
+func (lfo *LFO) GetNextSample() float64 {
+
+	frequency := lfo.Generator.Frequency
+	lfo.Generator.Frequency += lfo.RateMod.GetNextSample()
+
+	sample := lfo.Generator.GetNextSample()
+
+	lfo.Generator.Frequency = frequency
+
+	if lfo.shape != LFOWave {
+
+		if wrapped, _ := lfo.Generator.CycleWrapped(); wrapped {
+			lfo.prevValue = lfo.nextValue
+			lfo.nextValue = lfo.getRandomValue()
+		}
+
+		if lfo.shape == LFOSampleAndHold {
+			sample = lfo.prevValue
+		} else {
+			// cosine interpolation, so the slope is 0 at the cycle wraps
+			pos := (1 - math.Cos(math.Pi*lfo.Generator.GetPhase())) / 2
+			sample = lfo.prevValue + (lfo.nextValue-lfo.prevValue)*pos
+		}
+	}
+
+	if lfo.Unipolar {
+		sample = (sample + 1) / 2
+	}
+
+	return lfo.Offset + lfo.Deviation*sample*lfo.getFade()
 }
 
-// Reset simply resets the generator for the LFO.
+// Reset resets the generator, the random values and the delay for the LFO.
 func (lfo *LFO) Reset() {
 	lfo.Generator.Reset()
+	lfo.RateMod.Reset()
+	lfo.SetSeed(lfo.rndSeed)
+	lfo.currS = 0
+}
+
+// Clone returns an independent copy of the LFO with the same settings.
+// Implements the generator.Cloner interface.
+func (lfo *LFO) Clone() generator.Generator {
+	lfoTmp := *lfo
+	lfoTmp.Generator = *lfo.Generator.Clone().(*generator.FunctionGenerator)
+	if cloner, ok := lfo.RateMod.(generator.Cloner); ok {
+		lfoTmp.RateMod = cloner.Clone()
+	}
+	lfoTmp.SetSeed(lfo.rndSeed)
+	return &lfoTmp
 }
@@ -0,0 +1,294 @@
+package modulator
+
+import (
+	"fmt"
+
+	"github.com/rawbits2010/LibBitDauer/package/synth/generator"
+)
+
+type ModSource struct {
+	matrix *ModMatrix
+	source generator.Generator
+	value  float64 // the value of the last update
+}
+
+// GetGenerator returns the generator of the source.
+func (ms ModSource) GetGenerator() generator.Generator {
+	return ms.source
+}
+
+type ModRoute struct {
+	Depth float64 // multiplier for the source value
+
+	source      *ModSource
+	destination *ModDestination
+}
+
+// GetSource returns the source of the route.
+func (mr ModRoute) GetSource() *ModSource {
+	return mr.source
+}
+
+// GetDestination returns the destination of the route.
+func (mr ModRoute) GetDestination() *ModDestination {
+	return mr.destination
+}
+
+type ModDestination struct {
+	sampleRate uint
+	matrix     *ModMatrix
+	destIdx    int // the position in the matrix, the same in the copies
+	value      float64
+}
+
+// GetSampleRate returns the sample rate of the matrix.
+func (md ModDestination) GetSampleRate() uint {
+	return md.sampleRate
+}
+
+// GetNextSample returns the sum of the routed sources with their depth, as
+// of the last matrix update.
+func (md ModDestination) GetNextSample() float64 {
+	return md.value
+}
+
+// Reset does nothing, the value is updated by the matrix.
+func (md *ModDestination) Reset() {}
+
+// Clone returns the same destination of the last copy of the matrix, so the
+// modulation inputs cloned after the matrix are plugged into the copy, see
+// ModMatrix.Clone. If the matrix was never copied, e.g. it modulates all
+// the voices at once, the destination itself is returned. Implements the
+// generator.Cloner interface.
+func (md *ModDestination) Clone() generator.Generator {
+	if md.matrix == nil || md.matrix.lastCopy == nil {
+		return md
+	}
+	return md.matrix.lastCopy.destinations[md.destIdx]
+}
+
+type ModMatrix struct {
+	Output    generator.Generator // the generator the destinations are plugged into
+	BlockSize uint                // the destinations are updated every BlockSize samples
+
+	sampleRate   uint
+	sources      []*ModSource
+	destinations []*ModDestination
+	routes       []*ModRoute
+	currS        uint
+	lastCopy     *ModMatrix // the last copy made by Clone, see ModDestination.Clone
+}
+
+// NewModMatrix creates a modulation matrix that implements the Generator
+// interface. Sources are routed with a depth to destinations, which are
+// generators themselves and can be plugged into any modulation input, e.g.
+// Oscillator.FrequencyMod or LFO.RateMod. A source can be routed to
+// multiple destinations, it is still only advanced once per sample.
+//
+// The sources are advanced by the matrix, so they must not be advanced
+// anywhere else, like inside the Output, or they run at multiple speed.
+// To route the velocity or the envelope levels of an oscillator, use the
+// sources it provides, e.g. Oscillator.VelocitySource, which only read them.
+//
+// The matrix advances the sources and updates the destinations, then
+// returns the next sample of the Output. So render the matrix instead of
+// the Output. With a BlockSize above 1 the destinations only change once
+// per block, which saves calculations in the modulated objects.
+//
+// A matrix rendered this way modulates everything plugged into it at once,
+// e.g. all the voices of a voice manager. For modulation per voice, set it
+// as the Oscillator.ModMatrix of the template without an Output instead,
+// then every voice gets its own copy.
+// The sample rate is in Hz and can't be changed later.
+func NewModMatrix(sampleRate uint) *ModMatrix {
+	return &ModMatrix{
+		BlockSize:    1,
+		sampleRate:   sampleRate,
+		sources:      make([]*ModSource, 0),
+		destinations: make([]*ModDestination, 0),
+		routes:       make([]*ModRoute, 0),
+	}
+}
+
+// GetSampleRate returns the sample rate with which the matrix was created.
+func (mm ModMatrix) GetSampleRate() uint {
+	return mm.sampleRate
+}
+
+// NewDestination creates a new destination which outputs the sum of the
+// sources routed to it.
+func (mm *ModMatrix) NewDestination() *ModDestination {
+	dest := &ModDestination{
+		sampleRate: mm.sampleRate,
+		matrix:     mm,
+		destIdx:    len(mm.destinations),
+	}
+	mm.destinations = append(mm.destinations, dest)
+	return dest
+}
+
+// AddSource adds a generator to the sources of the matrix, and returns the
+// source to be used in the routes. Add a generator only once and use the
+// same source for all its routes, because every added source is advanced.
+func (mm *ModMatrix) AddSource(source generator.Generator) (*ModSource, error) {
+
+	if source == nil {
+		return nil, fmt.Errorf("missing source")
+	}
+
+	src := &ModSource{
+		matrix: mm,
+		source: source,
+	}
+	mm.sources = append(mm.sources, src)
+
+	return src, nil
+}
+
+// GetSources returns the sources in the order they were added.
+func (mm ModMatrix) GetSources() []*ModSource {
+	return mm.sources
+}
+
+// AddRoute routes a source to a destination with a depth. Both must belong
+// to this matrix. The returned route can be used to change the depth later.
+func (mm *ModMatrix) AddRoute(source *ModSource, dest *ModDestination, depth float64) (*ModRoute, error) {
+
+	if source == nil || source.matrix != mm {
+		return nil, fmt.Errorf("the source is not in the matrix")
+	}
+	if dest == nil || dest.matrix != mm {
+		return nil, fmt.Errorf("the destination is not in the matrix")
+	}
+
+	route := &ModRoute{
+		Depth:       depth,
+		source:      source,
+		destination: dest,
+	}
+	mm.routes = append(mm.routes, route)
+
+	return route, nil
+}
+
+// GetRoutes returns the routes in the order they were added.
+func (mm ModMatrix) GetRoutes() []*ModRoute {
+	return mm.routes
+}
+
+// ClearRoutes removes all the routes and sources, the removed sources can't
+// be routed anymore. The destinations are kept but output 0.
+func (mm *ModMatrix) ClearRoutes() {
+	for _, src := range mm.sources {
+		src.matrix = nil
+	}
+	mm.sources = make([]*ModSource, 0)
+	mm.routes = make([]*ModRoute, 0)
+	for _, dest := range mm.destinations {
+		dest.value = 0
+	}
+}
+
+// Update advances the sources, and recalculates the destinations at the
+// start of every block. It is called by GetNextSample, or by the oscillator
+// for its ModMatrix.
+func (mm *ModMatrix) Update() {
+
+	for _, src := range mm.sources {
+		src.value = src.source.GetNextSample()
+	}
+
+	blockSize := mm.BlockSize
+	if blockSize < 1 {
+		blockSize = 1
+	}
+
+	if mm.currS%blockSize == 0 {
+
+		for _, dest := range mm.destinations {
+			dest.value = 0
+		}
+
+		for _, route := range mm.routes {
+			route.destination.value += route.source.value * route.Depth
+		}
+	}
+
+	mm.currS++
+}
+
+// GetNextSample updates the modulation and returns the next sample of the
+// Output, or 0 if there is none.
+func (mm *ModMatrix) GetNextSample() float64 {
+
+	mm.Update()
+
+	if mm.Output == nil {
+		return 0
+	}
+	return mm.Output.GetNextSample()
+}
+
+// Trigger triggers the sources that implement the Triggerable interface,
+// resets the rest, and starts a new block. Implements the Triggerable
+// interface.
+func (mm *ModMatrix) Trigger() {
+
+	mm.currS = 0
+	for _, src := range mm.sources {
+		if triggerable, ok := src.source.(Triggerable); ok {
+			triggerable.Trigger()
+		} else {
+			src.source.Reset()
+		}
+	}
+}
+
+// Reset resets the sources and the Output.
+func (mm *ModMatrix) Reset() {
+
+	mm.currS = 0
+	for _, src := range mm.sources {
+		src.source.Reset()
+	}
+	for _, dest := range mm.destinations {
+		dest.value = 0
+	}
+
+	if mm.Output != nil {
+		mm.Output.Reset()
+	}
+}
+
+// Clone returns a copy of the matrix with the same routes and its own
+// destinations, without an Output. The sources are cloned if they implement
+// the generator.Cloner interface, otherwise they are shared with the copy.
+// Until the next copy, the destinations of this matrix clone to the ones of
+// the copy, so clone the matrix before the objects plugged into it, like
+// Oscillator.Clone does. Implements the generator.Cloner interface.
+func (mm *ModMatrix) Clone() generator.Generator {
+
+	mmTmp := NewModMatrix(mm.sampleRate)
+	mmTmp.BlockSize = mm.BlockSize
+	mm.lastCopy = mmTmp
+
+	for range mm.destinations {
+		mmTmp.NewDestination()
+	}
+
+	// after the destinations, the sources can be plugged into them too
+	sourceMap := make(map[*ModSource]*ModSource, len(mm.sources))
+	for _, src := range mm.sources {
+		source := src.source
+		if cloner, ok := source.(generator.Cloner); ok {
+			source = cloner.Clone()
+		}
+		sourceMap[src], _ = mmTmp.AddSource(source)
+	}
+
+	for _, route := range mm.routes {
+		mmTmp.AddRoute(sourceMap[route.source], mmTmp.destinations[route.destination.destIdx], route.Depth)
+	}
+
+	return mmTmp
+}
@@ -0,0 +1,272 @@
+package modulator
+
+import (
+	"fmt"
+
+	"github.com/rawbits2010/LibBitDauer/package/synth/buffer"
+	"github.com/rawbits2010/LibBitDauer/package/synth/generator"
+	"github.com/rawbits2010/LibBitDauer/package/synth/modulator/easing"
+)
+
+type EnvelopeSegment struct {
+	Level   float64       // the level at the end of the segment
+	Curve   easing.Easing // the shape of the transition to Level
+	lengthS uint
+}
+
+type MultiSegmentEnvelope struct {
+	StartLevel float64 // the level before the first segment
+
+	segments     []EnvelopeSegment
+	sustainPoint int // index of the segment the level is held after, -1 if none
+	loopStart    int // index of the first looped segment, -1 if none
+	loopEnd      int // index of the last looped segment, -1 if none
+
+	sampleRate       uint
+	currSegment      int
+	currSample       uint
+	segmentStart     float64 // the level the current segment starts from
+	currLevel        float64
+	started          bool // StartLevel is picked up on the first sample
+	sustaining       bool
+	releaseTriggered bool
+	gate             bool // true between Trigger and TriggerRelease
+}
+
+// NewMultiSegmentEnvelope creates a new free-form envelope that implements
+// the Generator interface. It is built from segments added with AddSegment,
+// each moving from the level of the previous one to its own level with its
+// own easing function.
+//
+// With a sustain point set by SetSustainPoint, the level is held after that
+// segment until TriggerRelease. With a loop set by SetLoop, the segments
+// between the loop start and end repeat until TriggerRelease. On release
+// the envelope jumps to the segment after the sustain point or the loop
+// end, starting from the current level. Without either the envelope simply
+// plays through and holds the level of the last segment. It implements the
+// Envelope interface, so it can replace the ADSR in the oscillators, see
+// Oscillator.EnvelopeOverride.
+// The sample rate is in Hz and can't be changed afterwards.
+func NewMultiSegmentEnvelope(sampleRate uint) *MultiSegmentEnvelope {
+	return &MultiSegmentEnvelope{
+		segments:     make([]EnvelopeSegment, 0),
+		sustainPoint: -1,
+		loopStart:    -1,
+		loopEnd:      -1,
+		sampleRate:   sampleRate,
+	}
+}
+
+// GetSampleRate returns the sample rate with which the envelope
+// was created.
+func (mse MultiSegmentEnvelope) GetSampleRate() uint {
+	return mse.sampleRate
+}
+
+// AddSegment appends a segment that moves to level in durationMS
+// milliseconds using the curve. LERP is used if curve is nil.
+// Returns the index of the new segment.
+func (mse *MultiSegmentEnvelope) AddSegment(level float64, durationMS uint, curve easing.Easing) int {
+
+	if curve == nil {
+		curve = easing.NewLERP()
+	}
+
+	mse.segments = append(mse.segments, EnvelopeSegment{
+		Level:   level,
+		Curve:   curve,
+		lengthS: buffer.CalcSampleLength(mse.sampleRate, durationMS),
+	})
+
+	return len(mse.segments) - 1
+}
+
+// SetSegmentLength sets the length of the segment at segmentIdx in
+// milliseconds.
+func (mse *MultiSegmentEnvelope) SetSegmentLength(segmentIdx int, durationMS uint) error {
+	if segmentIdx < 0 || segmentIdx >= len(mse.segments) {
+		return fmt.Errorf("invalid segment index: %d", segmentIdx)
+	}
+	mse.segments[segmentIdx].lengthS = buffer.CalcSampleLength(mse.sampleRate, durationMS)
+	return nil
+}
+
+// GetSegments returns the segments, so their level and curve can be changed.
+func (mse MultiSegmentEnvelope) GetSegments() []EnvelopeSegment {
+	return mse.segments
+}
+
+// ClearSegments removes all the segments, the sustain point and the loop.
+func (mse *MultiSegmentEnvelope) ClearSegments() {
+	mse.segments = make([]EnvelopeSegment, 0)
+	mse.sustainPoint = -1
+	mse.ClearLoop()
+}
+
+// SetSustainPoint sets the segment the level is held after until
+// TriggerRelease. Use -1 to remove the sustain point.
+func (mse *MultiSegmentEnvelope) SetSustainPoint(segmentIdx int) error {
+	if segmentIdx < -1 || segmentIdx >= len(mse.segments) {
+		return fmt.Errorf("invalid segment index: %d", segmentIdx)
+	}
+	mse.sustainPoint = segmentIdx
+	return nil
+}
+
+// GetSustainPoint returns the index of the sustain segment, or -1 if none.
+func (mse MultiSegmentEnvelope) GetSustainPoint() int {
+	return mse.sustainPoint
+}
+
+// SetLoop sets the first and the last segment to repeat until
+// TriggerRelease. The first looped segment starts from the level of the
+// last one.
+func (mse *MultiSegmentEnvelope) SetLoop(startIdx, endIdx int) error {
+	if startIdx < 0 || startIdx >= len(mse.segments) {
+		return fmt.Errorf("invalid loop start index: %d", startIdx)
+	}
+	if endIdx < startIdx || endIdx >= len(mse.segments) {
+		return fmt.Errorf("invalid loop end index: %d", endIdx)
+	}
+	mse.loopStart = startIdx
+	mse.loopEnd = endIdx
+	return nil
+}
+
+// ClearLoop removes the loop.
+func (mse *MultiSegmentEnvelope) ClearLoop() {
+	mse.loopStart = -1
+	mse.loopEnd = -1
+}
+
+// GetLoop returns the first and the last looped segment, or -1 if none.
+func (mse MultiSegmentEnvelope) GetLoop() (int, int) {
+	return mse.loopStart, mse.loopEnd
+}
+
+// getReleaseSegment returns the index of the first segment after the
+// sustain point and the loop, or -1 if there is neither.
+func (mse MultiSegmentEnvelope) getReleaseSegment() int {
+	if mse.sustainPoint < 0 && mse.loopEnd < 0 {
+		return -1
+	}
+	if mse.sustainPoint > mse.loopEnd {
+		return mse.sustainPoint + 1
+	}
+	return mse.loopEnd + 1
+}
+
+// TriggerRelease starts the segments after the sustain point or the loop
+// from the current level, from any segment before them. Useful for
+// triggering on MIDI key release.
+func (mse *MultiSegmentEnvelope) TriggerRelease() {
+	mse.releaseTriggered = true
+	mse.sustaining = false
+	mse.gate = false
+
+	releaseSegment := mse.getReleaseSegment()
+	if releaseSegment >= 0 && mse.currSegment < releaseSegment {
+		mse.startSegment(releaseSegment)
+	}
+}
+
+// Trigger restarts the envelope from the first segment. It starts from the
+// current level, so a retriggered note doesn't click, or from the
+// StartLevel after a Reset. The velocity is ignored, the oscillators scale
+// the volume with it. Useful for triggering on MIDI key press.
+func (mse *MultiSegmentEnvelope) Trigger(velocity float64) {
+
+	if !mse.started {
+		mse.started = true
+		mse.currLevel = mse.StartLevel
+	}
+
+	mse.releaseTriggered = false
+	mse.sustaining = false
+	mse.gate = true
+	mse.startSegment(0)
+}
+
+// GetLevel returns the last envelope value.
+func (mse MultiSegmentEnvelope) GetLevel() float64 {
+	return mse.currLevel
+}
+
+// startSegment moves to the segment from the current level.
+func (mse *MultiSegmentEnvelope) startSegment(segmentIdx int) {
+	mse.currSegment = segmentIdx
+	mse.currSample = 0
+	mse.segmentStart = mse.currLevel
+}
+
+// finishSegment selects what comes after the current segment.
+func (mse *MultiSegmentEnvelope) finishSegment() {
+
+	finished := mse.currSegment
+
+	if !mse.releaseTriggered {
+
+		if finished == mse.loopEnd {
+			mse.startSegment(mse.loopStart)
+			return
+		}
+
+		if finished == mse.sustainPoint {
+			mse.sustaining = true
+		}
+	}
+
+	mse.startSegment(finished + 1)
+}
+
+// GetNextSample returns the next envelope value.
+func (mse *MultiSegmentEnvelope) GetNextSample() float64 {
+
+	if !mse.started {
+		mse.started = true
+		mse.currLevel = mse.StartLevel
+		mse.segmentStart = mse.StartLevel
+	}
+
+	if mse.sustaining || mse.currSegment >= len(mse.segments) {
+		return mse.currLevel
+	}
+
+	segment := mse.segments[mse.currSegment]
+
+	mse.currSample++
+	pos := segment.Curve.GetValue(0, segment.lengthS, mse.currSample)
+	mse.currLevel = mse.segmentStart + (segment.Level-mse.segmentStart)*pos
+
+	if mse.currSample >= segment.lengthS {
+		mse.finishSegment()
+	}
+
+	return mse.currLevel
+}
+
+// IsFinished returns true when all the segments are over and the envelope
+// only outputs the level of the last one. It is false while a Trigger is
+// held, even if the last segment is the sustain point.
+func (mse MultiSegmentEnvelope) IsFinished() bool {
+	return !mse.gate && mse.currSegment >= len(mse.segments)
+}
+
+// Clone returns an independent copy of the envelope with the same settings.
+// Implements the generator.Cloner interface.
+func (mse *MultiSegmentEnvelope) Clone() generator.Generator {
+	mseTmp := *mse
+	mseTmp.segments = make([]EnvelopeSegment, len(mse.segments))
+	copy(mseTmp.segments, mse.segments)
+	return &mseTmp
+}
+
+// Reset sets the envelope back to the start of the first segment.
+func (mse *MultiSegmentEnvelope) Reset() {
+	mse.releaseTriggered = false
+	mse.sustaining = false
+	mse.gate = false
+	mse.started = false
+	mse.currSegment = 0
+	mse.currSample = 0
+}
@@ -1,23 +1,31 @@
 package synth
 
-import "math"
+// defaultTuning is used by GetFreqOf, it's 12 note equal temperament with
+// A4 at 440 Hz until changed.
+var defaultTuning = NewEqualTuning(440)
 
-// NOTE: only 12 note system is supported - microtonal stuff maybe later
-var freqTable [108]float64
-
-// GenerateFreqTableBasedOn generates a 12 note LUT based on an arbitrary
-// frequency in Hz used as the A note of the 4th octave. Can't use the LUT
-// before this.
+// GenerateFreqTableBasedOn sets the default tuning to a 12 note equal
+// temperament based on an arbitrary frequency in Hz used as the A note of
+// the 4th octave. It's 440 Hz until this is called.
 // This value changed a lot throughout history. Also, some instruments are
-// tuned differently - currently only 1 LUT is supported. Use GetFreqOf to
-// get the frequency of a note.
+// tuned differently - create a Tuning for those. Use GetFreqOf to get the
+// frequency of a note.
 func GenerateFreqTableBasedOn(freqOfA4 float64) {
-	for octave := 0; octave <= 8; octave++ {
-		for note := 0; note < 12; note++ {
-			noteIdx := octave*12 + note
-			freqTable[noteIdx] = freqOfA4 * math.Pow(2, (float64(noteIdx-8)-49)/12)
-		}
+	defaultTuning = NewEqualTuning(freqOfA4)
+}
+
+// SetDefaultTuning sets the tuning used by GetFreqOf and the voice managers
+// without their own tuning. Nil sets the 440 Hz equal temperament.
+func SetDefaultTuning(tuning *Tuning) {
+	if tuning == nil {
+		tuning = NewEqualTuning(440)
 	}
+	defaultTuning = tuning
+}
+
+// GetDefaultTuning returns the tuning used by GetFreqOf.
+func GetDefaultTuning() *Tuning {
+	return defaultTuning
 }
 
 const (
@@ -40,8 +48,11 @@ const (
 	NoteH    uint8 = 11
 )
 
-// GetFreqOf returns the frequency of a note from the pre set LUT. Use
-// GenerateFreqTableBasedOn to generate it.
+// noteNames holds the names of the notes in an octave, with sharps.
+var noteNames = [12]string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+
+// GetFreqOf returns the frequency of a note from the default tuning. Use
+// GenerateFreqTableBasedOn or SetDefaultTuning to change it.
 func GetFreqOf(octave uint8, note uint8) float64 {
-	return freqTable[octave*12+note]
+	return defaultTuning.GetFreqOf(octave, note)
 }
@@ -0,0 +1,227 @@
+package synth
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type NoteNaming int
+
+const (
+	SharpNaming     NoteNaming = iota // C, C#, D ... A#, B
+	FlatNaming                        // C, Db, D ... Bb, B
+	GermanNaming                      // C, Cis, D ... B, H
+	HungarianNaming                   // C, Cisz, D ... B, H
+)
+
+// noteNamesFlat holds the names of the notes in an octave, with flats.
+var noteNamesFlat = [12]string{"C", "Db", "D", "Eb", "E", "F", "Gb", "G", "Ab", "A", "Bb", "B"}
+
+// noteNamesGerman holds the German names of the notes in an octave.
+var noteNamesGerman = [12]string{"C", "Cis", "D", "Dis", "E", "F", "Fis", "G", "Gis", "A", "B", "H"}
+
+// noteNamesHungarian holds the Hungarian names of the notes in an octave.
+var noteNamesHungarian = [12]string{"C", "Cisz", "D", "Disz", "E", "F", "Fisz", "G", "Gisz", "A", "B", "H"}
+
+// noteLetters holds the notes of the letters without accidentals.
+var noteLetters = map[byte]int{'C': 0, 'D': 2, 'E': 4, 'F': 5, 'G': 7, 'A': 9, 'B': 11, 'H': 11}
+
+// accidentals holds the spellings of the sharps and flats, longest first so
+// "isz" is not read as "is". The German and Hungarian spellings are only
+// accepted as the whole suffix.
+var accidentals = []struct {
+	spelling string
+	change   int
+}{
+	{"iszisz", 2}, {"eszesz", -2}, {"isis", 2}, {"eses", -2},
+	{"isz", 1}, {"esz", -1}, {"is", 1}, {"es", -1},
+	{"##", 2}, {"bb", -2}, {"x", 2},
+	{"#", 1}, {"♯", 1}, {"b", -1}, {"♭", -1},
+}
+
+// FormatNoteName returns the name of a MIDI note with the octave in
+// scientific pitch notation, e.g. "A4" for 69 or "C-1" for 0.
+func FormatNoteName(midiNote uint8, naming NoteNaming) string {
+
+	octave, note := SplitMIDINote(midiNote)
+
+	var name string
+	switch naming {
+	case FlatNaming:
+		name = noteNamesFlat[note]
+	case GermanNaming:
+		name = noteNamesGerman[note]
+	case HungarianNaming:
+		name = noteNamesHungarian[note]
+	default:
+		name = noteNames[note]
+	}
+
+	return name + strconv.Itoa(octave)
+}
+
+// ParseNoteName returns the MIDI note number of a note name in scientific
+// pitch notation, e.g. "A#4", "Bb3", "C-1", "Fisz2" or "Es5". The letter is
+// case-insensitive. The accidentals can be #, b, ♯, ♭, ## and x or bb, or
+// the German -is/-es and Hungarian -isz/-esz suffixes, including As, Asz,
+// Es and Esz. H is always B natural. B is B natural for SharpNaming and
+// FlatNaming, and B flat for GermanNaming and HungarianNaming.
+func ParseNoteName(name string, naming NoteNaming) (uint8, error) {
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return 0, fmt.Errorf("empty note name")
+	}
+
+	letter := strings.ToUpper(name[:1])[0]
+	note, ok := noteLetters[letter]
+	if !ok {
+		return 0, fmt.Errorf("invalid note letter in '%s'", name)
+	}
+	if letter == 'B' && (naming == GermanNaming || naming == HungarianNaming) {
+		note = 10
+	}
+
+	// the octave is the number at the end, it can be negative
+	octaveIdx := len(name)
+	for octaveIdx > 1 && name[octaveIdx-1] >= '0' && name[octaveIdx-1] <= '9' {
+		octaveIdx--
+	}
+	if octaveIdx > 1 && name[octaveIdx-1] == '-' {
+		octaveIdx--
+	}
+	if octaveIdx == len(name) {
+		return 0, fmt.Errorf("missing octave in '%s'", name)
+	}
+
+	octave, err := strconv.Atoi(name[octaveIdx:])
+	if err != nil {
+		return 0, fmt.Errorf("invalid octave in '%s'", name)
+	}
+
+	change, err := parseAccidental(letter, name[1:octaveIdx])
+	if err != nil {
+		return 0, fmt.Errorf("invalid accidental in '%s': %w", name, err)
+	}
+
+	midiNote := (octave+1)*12 + note + change
+	if midiNote < 0 || midiNote >= MIDINoteCount {
+		return 0, fmt.Errorf("note '%s' is out of the MIDI range", name)
+	}
+
+	return uint8(midiNote), nil
+}
+
+// parseAccidental returns the change in semitones of the accidentals after
+// the letter.
+func parseAccidental(letter byte, accidental string) (int, error) {
+
+	if accidental == "" {
+		return 0, nil
+	}
+
+	lower := strings.ToLower(accidental)
+
+	// As, Asz, Es and Esz drop the e of the flat suffix
+	if letter == 'A' || letter == 'E' {
+		switch lower {
+		case "s", "sz":
+			return -1, nil
+		case "ses", "szesz":
+			return -2, nil
+		}
+	}
+
+	for _, acc := range accidentals {
+		if lower == acc.spelling {
+			return acc.change, nil
+		}
+	}
+
+	// the symbols can be repeated, like "###"
+	change := 0
+	for _, char := range accidental {
+		switch char {
+		case '#', '♯':
+			change++
+		case 'b', '♭':
+			change--
+		default:
+			return 0, fmt.Errorf("unknown accidental '%s'", accidental)
+		}
+	}
+
+	return change, nil
+}
+
+// SplitMIDINote returns the octave and the note of a MIDI note number. The
+// octave of the notes below C0 is -1.
+func SplitMIDINote(midiNote uint8) (int, uint8) {
+	return int(midiNote)/12 - 1, midiNote % 12
+}
+
+// GetNearestNote returns the MIDI note closest to the frequency in Hz in
+// the tuning, and the difference from it in cents. Positive cents mean the
+// frequency is higher than the note. Returns false if there are no notes
+// or the frequency is not positive.
+func (tuning *Tuning) GetNearestNote(frequency float64) (uint8, float64, bool) {
+
+	if frequency <= 0 {
+		return 0, 0, false
+	}
+
+	found := false
+	var nearest uint8
+	var nearestCents float64
+	for midiNote, noteFreq := range tuning.freqTable {
+
+		if noteFreq <= 0 {
+			continue
+		}
+
+		cents := 1200 * math.Log2(frequency/noteFreq)
+		if !found || math.Abs(cents) < math.Abs(nearestCents) {
+			found = true
+			nearest = uint8(midiNote)
+			nearestCents = cents
+		}
+	}
+
+	return nearest, nearestCents, found
+}
+
+// GetNearestNote returns the MIDI note closest to the frequency in Hz in
+// the default tuning, and the difference from it in cents. See
+// Tuning.GetNearestNote.
+func GetNearestNote(frequency float64) (uint8, float64, bool) {
+	return defaultTuning.GetNearestNote(frequency)
+}
+
+// TransposeMIDINote returns the MIDI note moved by semitones, which can be
+// negative.
+func TransposeMIDINote(midiNote uint8, semitones int) (uint8, error) {
+	transposed := int(midiNote) + semitones
+	if transposed < 0 || transposed >= MIDINoteCount {
+		return 0, fmt.Errorf("transposed note %d is out of the MIDI range", transposed)
+	}
+	return uint8(transposed), nil
+}
+
+// TransposeFrequency returns the frequency in Hz moved by semitones in 12
+// note equal temperament. Fractions and negative values can be used.
+func TransposeFrequency(frequency, semitones float64) float64 {
+	return frequency * math.Pow(2, semitones/12)
+}
+
+// TransposeFrequencyByCents returns the frequency in Hz moved by cents.
+func TransposeFrequencyByCents(frequency, cents float64) float64 {
+	return frequency * math.Pow(2, cents/1200)
+}
+
+// GetCentsBetween returns the distance of two frequencies in cents,
+// positive if the second is higher.
+func GetCentsBetween(fromFrequency, toFrequency float64) float64 {
+	return 1200 * math.Log2(toFrequency/fromFrequency)
+}
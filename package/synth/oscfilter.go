@@ -0,0 +1,96 @@
+package synth
+
+import (
+	"math"
+
+	"github.com/rawbits2010/LibBitDauer/package/synth/filter"
+)
+
+// minFilterCutoff is the lowest cutoff frequency the filter stage sets, in Hz.
+const minFilterCutoff = 10
+
+// filterKeyTrackBase is the frequency where the key tracking doesn't change
+// the cutoff, in Hz. It's C4.
+const filterKeyTrackBase = 261.63
+
+// minFilterQ is the lowest quality factor the filter stage sets.
+const minFilterQ = 0.1
+
+// getFilterCutoff returns the cutoff frequency in Hz for the current state
+// of the filter envelope, the velocity and the frequency. It advances the
+// filter envelope.
+func (osc *Oscillator) getFilterCutoff(frequency float64) float64 {
+
+	octaves := osc.FilterVelocityAmount * osc.velocity
+	if osc.UseFilterEnvelope {
+		octaves += osc.FilterEnvAmount * osc.getFilterEnvelope().GetNextSample()
+	}
+
+	cutoff := (osc.FilterCutoff + osc.FilterCutoffMod.GetNextSample()) * math.Pow(2, octaves)
+
+	if osc.FilterKeyTracking != 0 && frequency > 0 {
+		cutoff *= math.Pow(frequency/filterKeyTrackBase, osc.FilterKeyTracking)
+	}
+
+	nyquist := float64(osc.sampleRate) / 2
+	return math.Min(math.Max(cutoff, minFilterCutoff), nyquist*0.99)
+}
+
+// setFilterParams sets the cutoff frequency in Hz and the quality factor on
+// the filter if it supports them. The quality factor is only set if FilterQ
+// is not 0.
+func setFilterParams(flt filter.Filter, cutoff, q float64) {
+
+	if tunable, ok := flt.(filter.Tunable); ok {
+		tunable.SetCutoff(cutoff)
+	}
+
+	if q == 0 {
+		return
+	}
+	if resonant, ok := flt.(filter.Resonant); ok {
+		resonant.SetQualityFactor(q)
+	}
+}
+
+// applyFilter runs the samples through the filter stage. Only the mono or
+// the stereo samples are processed, as each needs separate filter states.
+func (osc *Oscillator) applyFilter(frequency, sample, left, right float64, stereo bool) (float64, float64, float64) {
+
+	if osc.Filter == nil {
+		return sample, left, right
+	}
+
+	cutoff := osc.getFilterCutoff(frequency)
+
+	qMod := osc.FilterQMod.GetNextSample()
+	var q float64
+	if osc.FilterQ != 0 {
+		q = math.Max(osc.FilterQ+qMod, minFilterQ)
+	}
+
+	setFilterParams(osc.Filter, cutoff, q)
+
+	if !stereo {
+		return osc.Filter.Filter(sample), left, right
+	}
+
+	if osc.filterRight == nil {
+		osc.filterRight = filter.Clone(osc.Filter)
+	}
+	if osc.filterRight != osc.Filter {
+		setFilterParams(osc.filterRight, cutoff, q)
+	}
+
+	return sample, osc.Filter.Filter(left), osc.filterRight.Filter(right)
+}
+
+// resetFilter resets the filters.
+func (osc *Oscillator) resetFilter() {
+
+	osc.filterRight = nil
+
+	if osc.Filter != nil {
+		osc.Filter.Reset()
+	}
+}
@@ -1,9 +1,13 @@
 package synth
 
 import (
+	"math/rand"
+
 	"github.com/rawbits2010/LibBitDauer/package/synth/buffer"
+	"github.com/rawbits2010/LibBitDauer/package/synth/filter"
 	"github.com/rawbits2010/LibBitDauer/package/synth/generator"
 	"github.com/rawbits2010/LibBitDauer/package/synth/modulator"
+	"github.com/rawbits2010/LibBitDauer/package/synth/modulator/easing"
 )
 
 type OscGeneratorType uint
@@ -12,45 +16,129 @@ const (
 	OscTypeWave OscGeneratorType = iota
 	OscTypePulse
 	OscTypeNoise
+	OscTypeSample
+)
+
+type AudioModType uint
+
+const (
+	AudioModOff  AudioModType = iota
+	RingMod                   // multiplies the sample with the modulator
+	AmplitudeMod              // scales the volume by the modulator around 1
+	PhaseMod                  // shifts the phase of the wave by the modulator
 )
 
 // TODO: extract delay, so GetNextSample don't need to modify the object
 type Oscillator struct {
 	Wave          *generator.FunctionGenerator
-	Pulse         *generator.Generator //TODO
+	Pulse         *generator.PulseGenerator
 	Noise         *generator.NoiseGenerator
+	Sample        *generator.SampleGenerator
 	generatorType OscGeneratorType // select one of the above
 
 	Frequency    float64
 	FrequencyMod generator.Generator // modulates Frequency
 
+	GlideMode    GlideMode     // select when a new Frequency glides
+	GlideCurve   GlideCurve    // glide evenly in pitch or in Hz
+	GlideEasing  easing.Easing // shape of the glide
+	glideS       float64       // glide time, or time per octave if glideByRate
+	glideByRate  bool
+	glideFrom    float64
+	glideTarget  float64
+	glideCurrent float64
+	glideLengthS uint
+	currGlideS   uint
+
 	Volume    float64
 	VolumeMod generator.Generator // modulates Volume
 
-	Envelope    *modulator.ADSR
-	UseEnvelope bool // turn on the envelope
+	AudioMod      generator.Generator // audio rate modulator, e.g. another oscillator
+	AudioModType  AudioModType        // select how AudioMod is applied
+	AudioModDepth float64             // [0-1] for ring and AM, radians for PM
+
+	Envelope         *modulator.ADSR
+	EnvelopeOverride modulator.Envelope // used instead of Envelope if set, e.g. a MultiSegmentEnvelope
+	UseEnvelope      bool               // turn on the envelope
+	Legato           bool               // a NoteOn while a note is held only changes the frequency
+
+	Filter                 filter.Filter       // optional filter after the generator
+	FilterCutoff           float64             // base cutoff frequency in Hz
+	FilterCutoffMod        generator.Generator // modulates FilterCutoff
+	FilterEnvelope         *modulator.ADSR     // sweeps the cutoff
+	FilterEnvelopeOverride modulator.Envelope  // used instead of FilterEnvelope if set
+	UseFilterEnvelope      bool                // turn on the filter envelope
+	FilterEnvAmount        float64             // cutoff change in octaves at full envelope
+	FilterVelocityAmount   float64             // cutoff change in octaves at full velocity
+	FilterKeyTracking      float64             // [0-1] how much the cutoff follows the Frequency
+	FilterQ                float64             // quality factor, 0 leaves the filter's own
+	FilterQMod             generator.Generator // modulates FilterQ
+	filterRight            filter.Filter       // copy of Filter for the right channel
+
+	Pan    float64             // [-1-1] stereo position, left to right
+	PanMod generator.Generator // modulates Pan
+
+	ModMatrix *modulator.ModMatrix // per-voice modulation, updated on every sample, can be nil
+
+	UnisonDetune      float64     // detune of the outermost copies in cents
+	UnisonCurve       DetuneCurve // spacing of the copies
+	UnisonSpread      float64     // [0-1] stereo width of the copies
+	UnisonRandomPhase bool        // random start phase for each copy
+	UnisonSeed        int64       // seed of the random start phases
+	unisonVoices      int
+	unisonWaves       []*generator.FunctionGenerator
+	unisonRnd         *rand.Rand // for the random start phases, reseeded on every restart
 
 	sampleRate uint
 
 	delayS     uint // delays the generator start
 	currDelayS uint
+
+	velocity  float64 // set by NoteOn, see VelocitySource
+	gate      bool    // true between NoteOn and NoteOff
+	lastLevel float64 // loudness of the last sample, used for voice stealing
+
+	cloning *Oscillator // the copy while Clone runs, for the bound sources
 }
 
 // NewOscillator creates a new oscillator which has a function generator,
 // an optional envelope, a starting volume and frequency value with
-// modulation options. The sample rate is in Hz and can't be changed later.
+// modulation options. An optional filter stage can be set with its own
+// envelope, the cutoff is set on every sample for filters that implement
+// the filter.Tunable interface. For stereo output the filter is cloned for
+// the right channel if it implements the filter.Cloner interface. The
+// quality factor is set the same way for filters that implement the
+// filter.Resonant interface. For a filter.FilterChain with multiple stages,
+// use SetTunedFilter to select the one that follows the cutoff.
+// For per-voice modulation set a modulator.ModMatrix without an Output as
+// the ModMatrix, it is cloned with the oscillator, and the modulation inputs
+// plugged into it are plugged into the copy.
+// The sample rate is in Hz and can't be changed later.
 func NewOscillator(sampleRate uint) *Oscillator {
 	oscTmp := &Oscillator{
-		Wave: generator.NewFunctionGenerator(sampleRate),
-		//TODO: Pulse: generator.NewPulseGenerator(sampleRate),
-		Noise:         generator.NewNoiseGenerator(sampleRate),
-		generatorType: OscTypeWave,
-		FrequencyMod:  modulator.NewFlatModulation(0),
-		Volume:        1,
-		VolumeMod:     modulator.NewFlatModulation(0),
-		Envelope:      modulator.NewADSR(sampleRate),
-		UseEnvelope:   false,
-		sampleRate:    sampleRate,
+		Wave:            generator.NewFunctionGenerator(sampleRate),
+		Pulse:           generator.NewPulseGenerator(sampleRate),
+		Noise:           generator.NewNoiseGenerator(sampleRate),
+		Sample:          generator.NewSampleGenerator(sampleRate),
+		generatorType:   OscTypeWave,
+		FrequencyMod:    modulator.NewFlatModulation(0),
+		GlideMode:       GlideOff,
+		GlideEasing:     easing.NewLERP(),
+		Volume:          1,
+		VolumeMod:       modulator.NewFlatModulation(0),
+		AudioMod:        modulator.NewFlatModulation(0),
+		AudioModType:    AudioModOff,
+		AudioModDepth:   1,
+		Envelope:        modulator.NewADSR(sampleRate),
+		UseEnvelope:     false,
+		FilterCutoff:    float64(sampleRate) / 2,
+		FilterCutoffMod: modulator.NewFlatModulation(0),
+		FilterEnvelope:  modulator.NewADSR(sampleRate),
+		FilterQMod:      modulator.NewFlatModulation(0),
+		PanMod:          modulator.NewFlatModulation(0),
+		sampleRate:      sampleRate,
+		unisonVoices:    1,
+		velocity:        1,
 	}
 
 	return oscTmp
@@ -67,6 +155,37 @@ func (osc *Oscillator) SetDelay(durationMS uint) {
 	osc.delayS = buffer.CalcSampleLength(osc.sampleRate, durationMS)
 }
 
+// SyncTo hard syncs the wave and pulse generators to the master oscillator,
+// so their cycle restarts whenever the master completes one. The master is
+// advanced by this oscillator, so don't use it anywhere else. Use nil to
+// turn off the sync.
+func (osc *Oscillator) SyncTo(master *Oscillator) {
+	if master == nil {
+		osc.Wave.SetSyncSource(nil)
+		osc.Pulse.SetSyncSource(nil)
+		return
+	}
+	osc.Wave.SetSyncSource(master)
+	osc.Pulse.SetSyncSource(master)
+}
+
+// CycleWrapped returns true if the last GetNextSample call completed a
+// cycle of the wave or pulse generator, with the fraction [0-1) of the
+// sample step after the wrap. In unison the middle copy is followed, which
+// is the least detuned. Implements the generator.SyncSource interface.
+func (osc Oscillator) CycleWrapped() (bool, float64) {
+	switch osc.generatorType {
+	case OscTypeWave:
+		if len(osc.unisonWaves) > 1 {
+			return osc.unisonWaves[len(osc.unisonWaves)/2].CycleWrapped()
+		}
+		return osc.Wave.CycleWrapped()
+	case OscTypePulse:
+		return osc.Pulse.CycleWrapped()
+	}
+	return false, 0
+}
+
 // SwitchGeneratorType will instantly switch to another type of function
 // generator. Won't change any values for them.
 func (osc *Oscillator) SwitchGeneratorType(oscType OscGeneratorType) {
@@ -75,41 +194,375 @@ func (osc *Oscillator) SwitchGeneratorType(oscType OscGeneratorType) {
 
 // GetNextSample returns the next sample using the set oscillator state.
 func (osc *Oscillator) GetNextSample() float64 {
+	sample, _, _ := osc.getNextSamples(false)
+	return sample
+}
+
+// GetNextStereoSample returns the next left and right samples using the set
+// oscillator state, with the pan and the unison spread applied. Implements
+// the generator.StereoGenerator interface.
+func (osc *Oscillator) GetNextStereoSample() (float64, float64) {
+	_, left, right := osc.getNextSamples(true)
+	return left, right
+}
+
+// getNextSamples returns the next mono, left and right samples. The filter
+// is only applied to the mono or the stereo samples based on isStereo.
+func (osc *Oscillator) getNextSamples(isStereo bool) (float64, float64, float64) {
 
 	if osc.currDelayS < osc.delayS {
 		osc.currDelayS++
-		return 0
+		return 0, 0, 0
+	}
+
+	if osc.ModMatrix != nil {
+		osc.ModMatrix.Update()
+	}
+
+	var audioMod float64
+	if osc.AudioModType != AudioModOff {
+		audioMod = osc.AudioMod.GetNextSample()
 	}
 
 	var sample float64
+	var left, right float64
+	stereo := false
+
+	frequency := osc.getGlideFrequency() + osc.FrequencyMod.GetNextSample()
+	pan := osc.Pan + osc.PanMod.GetNextSample()
+
 	switch osc.generatorType {
 
 	case OscTypeWave:
-		osc.Wave.Frequency = osc.Frequency + osc.FrequencyMod.GetNextSample()
-		sample = osc.Wave.GetNextSample()
+
+		var phaseOffset float64
+		if osc.AudioModType == PhaseMod {
+			phaseOffset = audioMod * osc.AudioModDepth
+		}
+
+		if len(osc.unisonWaves) > 1 {
+			sample, left, right = osc.getUnisonSample(frequency, phaseOffset, pan)
+			stereo = true
+		} else {
+			osc.Wave.Frequency = frequency
+			osc.Wave.PhaseOffset = phaseOffset
+			sample = osc.Wave.GetNextSample()
+		}
 
 	case OscTypePulse:
-		// TODO
+		osc.Pulse.Frequency = frequency
+		sample = osc.Pulse.GetNextSample()
 
 	case OscTypeNoise:
 		sample = osc.Noise.GetNextSample()
+
+	case OscTypeSample:
+		osc.Sample.Frequency = frequency
+		sample = osc.Sample.GetNextSample()
+	}
+
+	if !stereo {
+		panLeft, panRight := getPanGains(pan)
+		left = sample * panLeft
+		right = sample * panRight
+	}
+
+	sample, left, right = osc.applyFilter(frequency, sample, left, right, isStereo)
+
+	audioGain := 1.0
+	switch osc.AudioModType {
+
+	case RingMod:
+		audioGain = 1 - osc.AudioModDepth + osc.AudioModDepth*audioMod
+
+	case AmplitudeMod:
+		audioGain = (1 + osc.AudioModDepth*audioMod) / (1 + osc.AudioModDepth)
 	}
 
-	sample *= osc.Volume + osc.VolumeMod.GetNextSample()
+	level := (osc.Volume + osc.VolumeMod.GetNextSample()) * osc.velocity
 
 	if osc.UseEnvelope {
-		sample *= osc.Envelope.GetNextSample()
+		level *= osc.getEnvelope().GetNextSample()
 	}
 
-	return sample
+	osc.lastLevel = level
+
+	gain := audioGain * level
+
+	return sample * gain, left * gain, right * gain
+}
+
+// NoteOn restarts the oscillator with a new frequency in Hz and a velocity
+// [0-1] which scales the volume. The envelopes hold the sustain until
+// NoteOff, and they restart from their current level. In Legato mode, if a
+// note is already held, only the frequency is changed. The frequency glides
+// from the last note based on the GlideMode. Implements the Voice interface.
+func (osc *Oscillator) NoteOn(frequency, velocity float64) {
+	legato := osc.gate && osc.Legato
+
+	osc.Frequency = frequency
+	if osc.GlideMode == GlideOff || (osc.GlideMode == GlideLegato && !osc.gate) {
+		osc.jumpGlide()
+	}
+	osc.gate = true
+
+	if legato {
+		return
+	}
+
+	osc.velocity = velocity
+	osc.restart()
+	osc.triggerModulators()
+	osc.getEnvelope().Trigger(velocity)
+	osc.getFilterEnvelope().Trigger(velocity)
+}
+
+// VelocitySource returns a generator that outputs the velocity of the last
+// NoteOn, so it can be used as a modulation source, e.g. in the ModMatrix.
+// When the oscillator is cloned, the copy of the source reads the copy of
+// the oscillator.
+func (osc *Oscillator) VelocitySource() generator.Generator {
+	return &velocitySource{osc: osc}
+}
+
+// EnvelopeSource returns a generator that outputs the last level of the
+// envelope without advancing it, so it can be used as a modulation source,
+// e.g. in the ModMatrix. When the oscillator is cloned, the copy of the
+// source reads the copy of the oscillator.
+func (osc *Oscillator) EnvelopeSource() generator.Generator {
+	return &envelopeSource{osc: osc}
+}
+
+// FilterEnvelopeSource returns a generator that outputs the last level of
+// the filter envelope, like EnvelopeSource.
+func (osc *Oscillator) FilterEnvelopeSource() generator.Generator {
+	return &envelopeSource{osc: osc, isFilter: true}
+}
+
+// NoteOff starts the release phase of the envelope, and leaves the loop of
+// the sample. Implements the Voice interface.
+func (osc *Oscillator) NoteOff() {
+	osc.gate = false
+	osc.getEnvelope().TriggerRelease()
+	osc.getFilterEnvelope().TriggerRelease()
+	osc.Sample.TriggerRelease()
+}
+
+// IsActive returns true while the oscillator is producing sound after a
+// NoteOn. With an envelope it stops at the end of the release phase. Without
+// one it stops at NoteOff, or at the end of the sound for samples.
+// Implements the Voice interface.
+func (osc Oscillator) IsActive() bool {
+	if osc.gate {
+		return true
+	}
+	if osc.UseEnvelope {
+		return !osc.getEnvelope().IsFinished()
+	}
+	if osc.generatorType == OscTypeSample {
+		return !osc.Sample.IsFinished()
+	}
+	return false
+}
+
+// GetLevel returns the volume of the last sample with the modulation,
+// velocity and envelope applied. Implements the Voice interface.
+func (osc Oscillator) GetLevel() float64 {
+	if osc.lastLevel < 0 {
+		return -osc.lastLevel
+	}
+	return osc.lastLevel
+}
+
+// Clone returns an independent copy of the oscillator with the same
+// settings. The modulators are cloned if they implement the
+// generator.Cloner interface, otherwise they are shared with the copy.
+// The ModMatrix is cloned first, so the modulation inputs plugged into it
+// are plugged into its copy. A sync master oscillator is cloned as well.
+func (osc *Oscillator) Clone() *Oscillator {
+	oscTmp := *osc
+
+	osc.cloning = &oscTmp
+	defer func() { osc.cloning = nil }()
+
+	if osc.ModMatrix != nil {
+		oscTmp.ModMatrix = osc.ModMatrix.Clone().(*modulator.ModMatrix)
+	}
+
+	oscTmp.Wave = osc.Wave.Clone().(*generator.FunctionGenerator)
+	oscTmp.Pulse = osc.Pulse.Clone().(*generator.PulseGenerator)
+	oscTmp.Noise = osc.Noise.Clone().(*generator.NoiseGenerator)
+	oscTmp.Sample = osc.Sample.Clone().(*generator.SampleGenerator)
+	oscTmp.Envelope = osc.Envelope.Clone().(*modulator.ADSR)
+	if osc.EnvelopeOverride != nil {
+		oscTmp.EnvelopeOverride = modulator.CloneEnvelope(osc.EnvelopeOverride)
+	}
+	oscTmp.FrequencyMod = cloneGenerator(osc.FrequencyMod)
+	oscTmp.VolumeMod = cloneGenerator(osc.VolumeMod)
+	oscTmp.AudioMod = cloneGenerator(osc.AudioMod)
+	oscTmp.Filter = filter.Clone(osc.Filter)
+	oscTmp.filterRight = nil
+	oscTmp.FilterCutoffMod = cloneGenerator(osc.FilterCutoffMod)
+	oscTmp.FilterQMod = cloneGenerator(osc.FilterQMod)
+	oscTmp.PanMod = cloneGenerator(osc.PanMod)
+	oscTmp.FilterEnvelope = osc.FilterEnvelope.Clone().(*modulator.ADSR)
+	if osc.FilterEnvelopeOverride != nil {
+		oscTmp.FilterEnvelopeOverride = modulator.CloneEnvelope(osc.FilterEnvelopeOverride)
+	}
+
+	if master, ok := osc.Wave.GetSyncSource().(*Oscillator); ok {
+		oscTmp.SyncTo(master.Clone())
+	}
+
+	oscTmp.buildUnison()
+
+	return &oscTmp
+}
+
+func cloneGenerator(gen generator.Generator) generator.Generator {
+	if cloner, ok := gen.(generator.Cloner); ok {
+		return cloner.Clone()
+	}
+	return gen
 }
 
-// Reset resets ALL the oscillator values to their default.
+// Reset resets ALL the oscillator values to their default. A held note
+// restarts its envelopes.
 func (osc *Oscillator) Reset() {
+	osc.jumpGlide()
+	osc.restart()
+	osc.resetModulators()
+	osc.getEnvelope().Reset()
+	osc.getFilterEnvelope().Reset()
+	if osc.gate {
+		osc.getEnvelope().Trigger(osc.velocity)
+		osc.getFilterEnvelope().Trigger(osc.velocity)
+	}
+}
+
+// getEnvelope returns the EnvelopeOverride if set, otherwise the Envelope.
+func (osc Oscillator) getEnvelope() modulator.Envelope {
+	if osc.EnvelopeOverride != nil {
+		return osc.EnvelopeOverride
+	}
+	return osc.Envelope
+}
+
+// getFilterEnvelope returns the FilterEnvelopeOverride if set, otherwise
+// the FilterEnvelope.
+func (osc Oscillator) getFilterEnvelope() modulator.Envelope {
+	if osc.FilterEnvelopeOverride != nil {
+		return osc.FilterEnvelopeOverride
+	}
+	return osc.FilterEnvelope
+}
+
+// restart resets the oscillator except the envelopes and the modulators.
+func (osc *Oscillator) restart() {
 	osc.currDelayS = 0
+	osc.lastLevel = 0
 	osc.Wave.Reset()
-	//TODO: osc.Pluse.Reset()
+	osc.Pulse.Reset()
 	osc.Noise.Reset()
-	osc.FrequencyMod.Reset()
-	osc.VolumeMod.Reset()
+	osc.Sample.Reset()
+	osc.resetFilter()
+	osc.resetUnison()
+}
+
+// getModulators returns all the modulation inputs.
+func (osc *Oscillator) getModulators() []generator.Generator {
+	return []generator.Generator{
+		osc.FrequencyMod,
+		osc.VolumeMod,
+		osc.AudioMod,
+		osc.PanMod,
+		osc.FilterCutoffMod,
+		osc.FilterQMod,
+	}
+}
+
+// resetModulators resets all the modulation inputs and the ModMatrix.
+func (osc *Oscillator) resetModulators() {
+	if osc.ModMatrix != nil {
+		osc.ModMatrix.Reset()
+	}
+	for _, mod := range osc.getModulators() {
+		mod.Reset()
+	}
+}
+
+// triggerModulators triggers the modulation inputs that implement the
+// modulator.Triggerable interface and the ModMatrix, and resets the rest.
+func (osc *Oscillator) triggerModulators() {
+	if osc.ModMatrix != nil {
+		osc.ModMatrix.Trigger()
+	}
+	for _, mod := range osc.getModulators() {
+		if triggerable, ok := mod.(modulator.Triggerable); ok {
+			triggerable.Trigger()
+		} else {
+			mod.Reset()
+		}
+	}
+}
+
+// velocitySource outputs the velocity of an oscillator as a generator.
+type velocitySource struct {
+	osc *Oscillator
+}
+
+// GetSampleRate returns the sample rate of the oscillator.
+func (vs velocitySource) GetSampleRate() uint {
+	return vs.osc.sampleRate
+}
+
+// GetNextSample returns the velocity of the last NoteOn.
+func (vs velocitySource) GetNextSample() float64 {
+	return vs.osc.velocity
+}
+
+// Reset does nothing, the velocity is set by NoteOn.
+func (vs *velocitySource) Reset() {}
+
+// Clone returns the velocity source of the copy while the oscillator is
+// cloned, otherwise the same source. Implements the generator.Cloner
+// interface.
+func (vs *velocitySource) Clone() generator.Generator {
+	if vs.osc.cloning != nil {
+		return vs.osc.cloning.VelocitySource()
+	}
+	return vs
+}
+
+// envelopeSource outputs the level of an envelope of an oscillator as a
+// generator.
+type envelopeSource struct {
+	osc      *Oscillator
+	isFilter bool // the FilterEnvelope instead of the Envelope
+}
+
+// GetSampleRate returns the sample rate of the oscillator.
+func (es envelopeSource) GetSampleRate() uint {
+	return es.osc.sampleRate
+}
+
+// GetNextSample returns the last level of the envelope.
+func (es envelopeSource) GetNextSample() float64 {
+	if es.isFilter {
+		return es.osc.getFilterEnvelope().GetLevel()
+	}
+	return es.osc.getEnvelope().GetLevel()
+}
+
+// Reset does nothing, the envelope is advanced by the oscillator.
+func (es *envelopeSource) Reset() {}
+
+// Clone returns the envelope source of the copy while the oscillator is
+// cloned, otherwise the same source. Implements the generator.Cloner
+// interface.
+func (es *envelopeSource) Clone() generator.Generator {
+	if es.osc.cloning != nil {
+		return &envelopeSource{osc: es.osc.cloning, isFilter: es.isFilter}
+	}
+	return es
 }
@@ -0,0 +1,262 @@
+package synth
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ScalaScale is a scale as described by a Scala .scl file. The degrees are
+// in cents from the first note of the scale, which is not listed. The last
+// degree is the period of the scale, usually an octave.
+type ScalaScale struct {
+	Description string
+	Degrees     []float64
+}
+
+// KeyboardMapping is a keyboard mapping as described by a Scala .kbm file.
+// It tells which MIDI note plays which scale degree, and which note has a
+// fixed reference frequency.
+type KeyboardMapping struct {
+	FirstNote     uint8   // the first MIDI note to retune
+	LastNote      uint8   // the last MIDI note to retune
+	MiddleNote    uint8   // the MIDI note the first entry of the Mapping is on
+	ReferenceNote uint8   // the MIDI note with the ReferenceFreq
+	ReferenceFreq float64 // in Hz
+	OctaveDegree  int     // the scale degree the Mapping repeats on, 0 is the period
+	Mapping       []int   // scale degree of the keys from MiddleNote, -1 is unmapped
+}
+
+// NewKeyboardMapping creates the default keyboard mapping, where every key
+// plays the next scale degree from middle C, and A4 is 440 Hz.
+func NewKeyboardMapping() *KeyboardMapping {
+	return &KeyboardMapping{
+		FirstNote:     0,
+		LastNote:      MIDINoteCount - 1,
+		MiddleNote:    60,
+		ReferenceNote: 69,
+		ReferenceFreq: 440,
+		OctaveDegree:  0,
+		Mapping:       nil,
+	}
+}
+
+// readScalaLines returns the lines of a Scala file without the comments.
+func readScalaLines(reader io.Reader) ([]string, error) {
+
+	lines := make([]string, 0)
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(line, "!") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// getFirstField returns the first whitespace separated field of the line,
+// the rest is ignored in Scala files.
+func getFirstField(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// parseScalaPitch returns a pitch of a Scala scale in cents. Values with a
+// period are in cents, the others are ratios like 3/2, or whole numbers.
+func parseScalaPitch(value string) (float64, error) {
+
+	if strings.Contains(value, ".") {
+		return strconv.ParseFloat(value, 64)
+	}
+
+	numerator, denominator := value, "1"
+	if slashIdx := strings.Index(value, "/"); slashIdx >= 0 {
+		numerator, denominator = value[:slashIdx], value[slashIdx+1:]
+	}
+
+	num, err := strconv.ParseFloat(numerator, 64)
+	if err != nil {
+		return 0, err
+	}
+	den, err := strconv.ParseFloat(denominator, 64)
+	if err != nil {
+		return 0, err
+	}
+	if num <= 0 || den <= 0 {
+		return 0, fmt.Errorf("invalid ratio: '%s'", value)
+	}
+
+	return 1200 * math.Log2(num/den), nil
+}
+
+// ParseScalaScale reads a scale in the Scala .scl format.
+func ParseScalaScale(reader io.Reader) (*ScalaScale, error) {
+
+	lines, err := readScalaLines(reader)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read scale: %w", err)
+	}
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("missing scale header")
+	}
+
+	count, err := strconv.Atoi(getFirstField(lines[1]))
+	if err != nil || count < 1 {
+		return nil, fmt.Errorf("invalid note count: '%s'", lines[1])
+	}
+
+	scale := &ScalaScale{
+		Description: strings.TrimSpace(lines[0]),
+		Degrees:     make([]float64, 0, count),
+	}
+
+	for _, line := range lines[2:] {
+		value := getFirstField(line)
+		if value == "" {
+			continue
+		}
+		if len(scale.Degrees) == count {
+			break
+		}
+
+		cents, err := parseScalaPitch(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pitch: '%s': %w", value, err)
+		}
+		scale.Degrees = append(scale.Degrees, cents)
+	}
+
+	if len(scale.Degrees) != count {
+		return nil, fmt.Errorf("expected %d notes, found %d", count, len(scale.Degrees))
+	}
+
+	return scale, nil
+}
+
+// ReadScalaScale loads a scale from a Scala .scl file.
+func ReadScalaScale(fileName string) (*ScalaScale, error) {
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open file: '%s': %w", fileName, err)
+	}
+	defer file.Close()
+
+	scale, err := ParseScalaScale(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scale file '%s': %w", fileName, err)
+	}
+
+	return scale, nil
+}
+
+// parseMIDINote returns a MIDI note number from a Scala file.
+func parseMIDINote(line string) (uint8, error) {
+	note, err := strconv.Atoi(getFirstField(line))
+	if err != nil || note < 0 || note >= MIDINoteCount {
+		return 0, fmt.Errorf("invalid MIDI note: '%s'", line)
+	}
+	return uint8(note), nil
+}
+
+// ParseKeyboardMapping reads a keyboard mapping in the Scala .kbm format.
+func ParseKeyboardMapping(reader io.Reader) (*KeyboardMapping, error) {
+
+	allLines, err := readScalaLines(reader)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read keyboard mapping: %w", err)
+	}
+
+	lines := make([]string, 0, len(allLines))
+	for _, line := range allLines {
+		if getFirstField(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) < 7 {
+		return nil, fmt.Errorf("missing keyboard mapping header")
+	}
+
+	size, err := strconv.Atoi(getFirstField(lines[0]))
+	if err != nil || size < 0 {
+		return nil, fmt.Errorf("invalid map size: '%s'", lines[0])
+	}
+
+	kbm := &KeyboardMapping{}
+
+	notes := []*uint8{&kbm.FirstNote, &kbm.LastNote, &kbm.MiddleNote, &kbm.ReferenceNote}
+	for noteIdx, note := range notes {
+		if *note, err = parseMIDINote(lines[noteIdx+1]); err != nil {
+			return nil, err
+		}
+	}
+
+	kbm.ReferenceFreq, err = strconv.ParseFloat(getFirstField(lines[5]), 64)
+	if err != nil || kbm.ReferenceFreq <= 0 {
+		return nil, fmt.Errorf("invalid reference frequency: '%s'", lines[5])
+	}
+
+	kbm.OctaveDegree, err = strconv.Atoi(getFirstField(lines[6]))
+	if err != nil || kbm.OctaveDegree < 0 {
+		return nil, fmt.Errorf("invalid octave degree: '%s'", lines[6])
+	}
+
+	if size == 0 {
+		return kbm, nil
+	}
+
+	// missing entries at the end are unmapped
+	kbm.Mapping = make([]int, size)
+	for keyIdx := range kbm.Mapping {
+
+		kbm.Mapping[keyIdx] = -1
+
+		if keyIdx+7 >= len(lines) {
+			continue
+		}
+
+		value := getFirstField(lines[keyIdx+7])
+		if value == "x" || value == "X" {
+			continue
+		}
+
+		degree, err := strconv.Atoi(value)
+		if err != nil || degree < 0 {
+			return nil, fmt.Errorf("invalid scale degree: '%s'", value)
+		}
+		kbm.Mapping[keyIdx] = degree
+	}
+
+	return kbm, nil
+}
+
+// ReadKeyboardMapping loads a keyboard mapping from a Scala .kbm file.
+func ReadKeyboardMapping(fileName string) (*KeyboardMapping, error) {
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open file: '%s': %w", fileName, err)
+	}
+	defer file.Close()
+
+	kbm, err := ParseKeyboardMapping(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keyboard mapping file '%s': %w", fileName, err)
+	}
+
+	return kbm, nil
+}
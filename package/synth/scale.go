@@ -0,0 +1,200 @@
+package synth
+
+import (
+	"fmt"
+	"math"
+)
+
+type ScaleType int
+
+const (
+	MajorScale ScaleType = iota
+	NaturalMinorScale
+	HarmonicMinorScale
+	MelodicMinorScale
+	DorianMode
+	PhrygianMode
+	LydianMode
+	MixolydianMode
+	LocrianMode
+	MajorPentatonicScale
+	MinorPentatonicScale
+	BluesScale
+	WholeToneScale
+	ChromaticScale
+)
+
+// IonianMode is the same as the major scale.
+const IonianMode = MajorScale
+
+// AeolianMode is the same as the natural minor scale.
+const AeolianMode = NaturalMinorScale
+
+// scaleDefinitions holds the names and the intervals of the scales in
+// semitones from the root.
+var scaleDefinitions = []Scale{
+	MajorScale:           {"Major", []int{0, 2, 4, 5, 7, 9, 11}},
+	NaturalMinorScale:    {"Natural minor", []int{0, 2, 3, 5, 7, 8, 10}},
+	HarmonicMinorScale:   {"Harmonic minor", []int{0, 2, 3, 5, 7, 8, 11}},
+	MelodicMinorScale:    {"Melodic minor", []int{0, 2, 3, 5, 7, 9, 11}},
+	DorianMode:           {"Dorian", []int{0, 2, 3, 5, 7, 9, 10}},
+	PhrygianMode:         {"Phrygian", []int{0, 1, 3, 5, 7, 8, 10}},
+	LydianMode:           {"Lydian", []int{0, 2, 4, 6, 7, 9, 11}},
+	MixolydianMode:       {"Mixolydian", []int{0, 2, 4, 5, 7, 9, 10}},
+	LocrianMode:          {"Locrian", []int{0, 1, 3, 5, 6, 8, 10}},
+	MajorPentatonicScale: {"Major pentatonic", []int{0, 2, 4, 7, 9}},
+	MinorPentatonicScale: {"Minor pentatonic", []int{0, 3, 5, 7, 10}},
+	BluesScale:           {"Blues", []int{0, 3, 5, 6, 7, 10}},
+	WholeToneScale:       {"Whole tone", []int{0, 2, 4, 6, 8, 10}},
+	ChromaticScale:       {"Chromatic", []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}},
+}
+
+type Scale struct {
+	Name      string
+	Intervals []int // semitones from the root in an octave, starting with 0
+}
+
+// NewScale returns one of the predefined scales.
+func NewScale(scaleType ScaleType) (*Scale, error) {
+	if scaleType < 0 || int(scaleType) >= len(scaleDefinitions) {
+		return nil, fmt.Errorf("invalid scale type: %d", scaleType)
+	}
+	definition := scaleDefinitions[scaleType]
+	return NewCustomScale(definition.Name, definition.Intervals)
+}
+
+// NewCustomScale creates a scale from a list of intervals in semitones from
+// the root. They must start with 0, and be ascending within an octave.
+func NewCustomScale(name string, intervals []int) (*Scale, error) {
+
+	if len(intervals) == 0 || intervals[0] != 0 {
+		return nil, fmt.Errorf("the intervals must start with 0")
+	}
+	for intervalIdx := 1; intervalIdx < len(intervals); intervalIdx++ {
+		if intervals[intervalIdx] <= intervals[intervalIdx-1] || intervals[intervalIdx] >= 12 {
+			return nil, fmt.Errorf("invalid interval: %d", intervals[intervalIdx])
+		}
+	}
+
+	scaleTmp := &Scale{
+		Name:      name,
+		Intervals: make([]int, len(intervals)),
+	}
+	copy(scaleTmp.Intervals, intervals)
+
+	return scaleTmp, nil
+}
+
+// GetMode returns the mode of the scale starting on a degree, e.g. the 2nd
+// mode of the major scale is dorian. The degree starts from 0.
+func (scale Scale) GetMode(degree int) *Scale {
+
+	_, degreeIdx := floorDivMod(degree, len(scale.Intervals))
+	start := scale.Intervals[degreeIdx]
+
+	modeTmp := &Scale{
+		Name:      fmt.Sprintf("%s mode %d", scale.Name, degreeIdx+1),
+		Intervals: make([]int, len(scale.Intervals)),
+	}
+	for intervalIdx := range modeTmp.Intervals {
+		_, idx := floorDivMod(degreeIdx+intervalIdx, len(scale.Intervals))
+		_, modeTmp.Intervals[intervalIdx] = floorDivMod(scale.Intervals[idx]-start, 12)
+	}
+
+	return modeTmp
+}
+
+// GetNote returns the MIDI note of the degree of the scale on the root MIDI
+// note. The degree starts from 0 and continues to the next octaves, it can
+// be negative for the notes below the root.
+func (scale Scale) GetNote(root uint8, degree int) (uint8, error) {
+
+	octave, degreeIdx := floorDivMod(degree, len(scale.Intervals))
+	midiNote := int(root) + octave*12 + scale.Intervals[degreeIdx]
+
+	if midiNote < 0 || midiNote >= MIDINoteCount {
+		return 0, fmt.Errorf("degree %d is out of the MIDI range", degree)
+	}
+
+	return uint8(midiNote), nil
+}
+
+// GetNotes returns the MIDI notes of the scale on the root MIDI note for a
+// number of octaves, with the root of the next octave at the end. The notes
+// above the MIDI range are left out.
+func (scale Scale) GetNotes(root uint8, octaves int) []uint8 {
+
+	notes := make([]uint8, 0, len(scale.Intervals)*octaves+1)
+	for degree := 0; degree <= len(scale.Intervals)*octaves; degree++ {
+		midiNote, err := scale.GetNote(root, degree)
+		if err != nil {
+			break
+		}
+		notes = append(notes, midiNote)
+	}
+
+	return notes
+}
+
+// Contains returns true if the MIDI note is in the scale on the root.
+func (scale Scale) Contains(root uint8, midiNote uint8) bool {
+	_, interval := floorDivMod(int(midiNote)-int(root), 12)
+	for _, scaleInterval := range scale.Intervals {
+		if scaleInterval == interval {
+			return true
+		}
+	}
+	return false
+}
+
+// Quantize returns the note of the scale on the root nearest to the MIDI
+// note. On a tie the lower note is returned.
+func (scale Scale) Quantize(root uint8, midiNote uint8) uint8 {
+
+	octave, interval := floorDivMod(int(midiNote)-int(root), 12)
+
+	nearest := 0
+	nearestDist := math.MaxInt
+	for _, scaleInterval := range scale.Intervals {
+		dist := interval - scaleInterval
+		if dist < 0 {
+			dist = -dist
+		}
+		if dist < nearestDist {
+			nearest = scaleInterval
+			nearestDist = dist
+		}
+	}
+
+	// the root of the next octave
+	if 12-interval < nearestDist {
+		nearest = 12
+	}
+
+	quantized := int(root) + octave*12 + nearest
+	if quantized >= MIDINoteCount {
+		quantized -= 12
+	}
+	if quantized < 0 {
+		quantized += 12
+	}
+
+	return uint8(quantized)
+}
+
+// GetChord returns the MIDI notes of a chord built from stacked thirds of
+// the scale on the root, starting from the degree, e.g. noteCount 3 gives
+// the triads and 4 the seventh chords.
+func (scale Scale) GetChord(root uint8, degree int, noteCount int) ([]uint8, error) {
+
+	notes := make([]uint8, noteCount)
+	for noteIdx := range notes {
+		midiNote, err := scale.GetNote(root, degree+noteIdx*2)
+		if err != nil {
+			return nil, err
+		}
+		notes[noteIdx] = midiNote
+	}
+
+	return notes, nil
+}
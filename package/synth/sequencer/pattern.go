@@ -0,0 +1,127 @@
+package sequencer
+
+import (
+	"fmt"
+
+	"github.com/rawbits2010/LibBitDauer/package/synth/buffer"
+)
+
+type Step struct {
+	Active      bool               // false for a rest
+	Note        uint8              // MIDI note number
+	Velocity    float64            // [0-1]
+	Gate        float64            // the length of the note relative to the step, above 1 it ties into the next steps
+	Probability float64            // the chance of the step playing [0-1], 0 never plays
+	Locks       map[string]float64 // parameter values for this step only, see Sequencer.AddParameter
+}
+
+// NewStep creates an active step that always plays the MIDI note at full
+// velocity for half the step. Create the steps with it and edit them from
+// there, because the zero value of Step is a rest, and a step with the zero
+// Probability never plays.
+func NewStep(note uint8) Step {
+	return Step{
+		Active:      true,
+		Note:        note,
+		Velocity:    1,
+		Gate:        0.5,
+		Probability: 1,
+	}
+}
+
+// SetLock sets the value of a parameter for this step only.
+func (step *Step) SetLock(name string, value float64) {
+	if step.Locks == nil {
+		step.Locks = make(map[string]float64)
+	}
+	step.Locks[name] = value
+}
+
+// ClearLocks removes all the parameter locks of the step.
+func (step *Step) ClearLocks() {
+	step.Locks = nil
+}
+
+type Pattern struct {
+	Division buffer.NoteDivision // the length of a step
+	Repeats  int                 // how many times the pattern plays in the chain, at least 1
+
+	steps []Step
+}
+
+// NewPattern creates a pattern with a number of steps, all rests. The
+// length of the steps is a note division, e.g. buffer.SixteenthNote.
+func NewPattern(stepCount int, div buffer.NoteDivision) (*Pattern, error) {
+
+	if stepCount < 1 {
+		return nil, fmt.Errorf("invalid step count: %d", stepCount)
+	}
+	if _, err := div.GetBeats(); err != nil {
+		return nil, err
+	}
+
+	return &Pattern{
+		Division: div,
+		Repeats:  1,
+		steps:    make([]Step, stepCount),
+	}, nil
+}
+
+// GetStepCount returns the number of steps in the pattern.
+func (pattern Pattern) GetStepCount() int {
+	return len(pattern.steps)
+}
+
+// SetStep replaces the step at the index.
+func (pattern *Pattern) SetStep(stepIdx int, step Step) error {
+	if stepIdx < 0 || stepIdx >= len(pattern.steps) {
+		return fmt.Errorf("invalid step index: %d", stepIdx)
+	}
+	pattern.steps[stepIdx] = step
+	return nil
+}
+
+// GetStep returns a pointer to the step at the index so it can be edited,
+// or nil if the index is invalid.
+func (pattern *Pattern) GetStep(stepIdx int) *Step {
+	if stepIdx < 0 || stepIdx >= len(pattern.steps) {
+		return nil
+	}
+	return &pattern.steps[stepIdx]
+}
+
+// SetNotes fills the steps from the first one with the MIDI notes, like
+// NewStep. The rest of the steps are left unchanged.
+func (pattern *Pattern) SetNotes(notes []uint8) {
+	for noteIdx, note := range notes {
+		if noteIdx >= len(pattern.steps) {
+			break
+		}
+		pattern.steps[noteIdx] = NewStep(note)
+	}
+}
+
+// ClearSteps sets all the steps to rests.
+func (pattern *Pattern) ClearSteps() {
+	for stepIdx := range pattern.steps {
+		pattern.steps[stepIdx] = Step{}
+	}
+}
+
+// GetBeats returns the length of one repeat of the pattern in beats, where
+// a beat is a quarter note.
+func (pattern Pattern) GetBeats() (float64, error) {
+	beats, err := pattern.Division.GetBeats()
+	if err != nil {
+		return 0, err
+	}
+	return beats * float64(len(pattern.steps)), nil
+}
+
+// getRepeats returns the number of repeats, at least 1.
+func (pattern Pattern) getRepeats() int {
+	if pattern.Repeats < 1 {
+		return 1
+	}
+	return pattern.Repeats
+}
@@ -0,0 +1,352 @@
+package sequencer
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/rawbits2010/LibBitDauer/package/synth"
+	"github.com/rawbits2010/LibBitDauer/package/synth/buffer"
+)
+
+type parameter struct {
+	set          func(value float64)
+	defaultValue float64
+	locked       bool // a lock is applied, the default is restored on the next step without one
+}
+
+type noteOff struct {
+	note   uint8
+	sample float64
+}
+
+type Sequencer struct {
+	Swing float64 // the position of the off-beat steps in a step pair [0.5-0.75], 0.5 is straight
+	Loop  bool    // start the chain over after the last pattern
+
+	voices     *synth.VoiceManager
+	bpm        float64
+	patterns   []*Pattern
+	parameters map[string]*parameter
+
+	patternIdx int
+	repeatIdx  int
+	stepIdx    int
+	stepCount  int     // the number of steps played in the pattern repeat, for the swing
+	currSample uint64  // the samples played since the start
+	gridPos    float64 // the sample of the current step without the swing
+	finished   bool    // the chain is over
+	noteOffs   []noteOff
+
+	rndSeed int64
+	rndFunc *rand.Rand
+}
+
+// NewSequencer creates a step sequencer that implements the Generator
+// interface. It plays the chain of patterns on the voice manager at the
+// tempo, default is 120 BPM, and returns the sound of the voices. The
+// notes are triggered on the exact sample of the steps.
+func NewSequencer(voices *synth.VoiceManager) *Sequencer {
+
+	seqTmp := &Sequencer{
+		Swing:      0.5,
+		Loop:       true,
+		voices:     voices,
+		bpm:        120,
+		patterns:   make([]*Pattern, 0),
+		parameters: make(map[string]*parameter),
+		noteOffs:   make([]noteOff, 0),
+	}
+	seqTmp.SetSeed(0)
+
+	return seqTmp
+}
+
+// GetSampleRate returns the sample rate of the voices.
+func (seq Sequencer) GetSampleRate() uint {
+	return seq.voices.GetSampleRate()
+}
+
+// GetVoiceManager returns the voice manager the notes are played on.
+func (seq Sequencer) GetVoiceManager() *synth.VoiceManager {
+	return seq.voices
+}
+
+// SetTempo sets the tempo in beats per minute. It takes effect from the
+// next step.
+func (seq *Sequencer) SetTempo(bpm float64) error {
+	if bpm <= 0 {
+		return fmt.Errorf("invalid tempo: %f BPM", bpm)
+	}
+	seq.bpm = bpm
+	return nil
+}
+
+// GetTempo returns the tempo in beats per minute.
+func (seq Sequencer) GetTempo() float64 {
+	return seq.bpm
+}
+
+// SetSeed sets the seed for the step probabilities for consistency.
+func (seq *Sequencer) SetSeed(seed int64) {
+	seq.rndSeed = seed
+	seq.rndFunc = rand.New(rand.NewSource(seed))
+}
+
+// AddPattern adds a pattern to the end of the chain. The same pattern can
+// be added multiple times, and it can be edited while playing.
+func (seq *Sequencer) AddPattern(pattern *Pattern) {
+	seq.patterns = append(seq.patterns, pattern)
+}
+
+// GetPatterns returns the patterns in the order they are chained.
+func (seq Sequencer) GetPatterns() []*Pattern {
+	return seq.patterns
+}
+
+// ClearPatterns empties the chain. The patterns added after it play from
+// their first step, starting on the next sample, even if the chain was
+// finished.
+func (seq *Sequencer) ClearPatterns() {
+	seq.patterns = make([]*Pattern, 0)
+
+	seq.patternIdx = 0
+	seq.repeatIdx = 0
+	seq.stepIdx = 0
+	seq.stepCount = 0
+	seq.gridPos = float64(seq.currSample)
+	seq.finished = false
+}
+
+// AddParameter registers a parameter for the step locks by name. The set
+// function is called with the value of the lock on the steps that lock the
+// parameter, and with the default value on the first step after that
+// doesn't, rests and skipped steps included.
+func (seq *Sequencer) AddParameter(name string, set func(value float64), defaultValue float64) {
+	seq.parameters[name] = &parameter{
+		set:          set,
+		defaultValue: defaultValue,
+	}
+}
+
+// RemoveParameter removes a parameter. The value is not restored.
+func (seq *Sequencer) RemoveParameter(name string) {
+	delete(seq.parameters, name)
+}
+
+// GetPosition returns the index of the pattern in the chain and the index
+// of the step in it that plays next.
+func (seq Sequencer) GetPosition() (int, int) {
+	return seq.patternIdx, seq.stepIdx
+}
+
+// IsFinished returns true if the chain is over and the voices are silent.
+// It never happens with Loop on.
+func (seq Sequencer) IsFinished() bool {
+	return seq.finished && seq.voices.GetActiveVoiceCount() == 0
+}
+
+// GetLength returns the length of one pass of the chain in samples at the
+// current tempo.
+func (seq Sequencer) GetLength() (float64, error) {
+
+	var beats float64
+	for _, pattern := range seq.patterns {
+		patternBeats, err := pattern.GetBeats()
+		if err != nil {
+			return 0, err
+		}
+		beats += patternBeats * float64(pattern.getRepeats())
+	}
+
+	return float64(seq.GetSampleRate()) * beats * buffer.CalcBeatLengthMS(seq.bpm) / 1000, nil
+}
+
+// Render resets the sequencer and renders one pass of the chain into a
+// buffer, with the tail in ms after the last step for the releases. Loop is
+// ignored while rendering.
+func (seq *Sequencer) Render(tailMS uint) ([]float64, error) {
+
+	length, err := seq.GetLength()
+	if err != nil {
+		return nil, err
+	}
+
+	buffSize := uint(math.Ceil(length)) + buffer.CalcSampleLength(seq.GetSampleRate(), tailMS)
+
+	loop := seq.Loop
+	seq.Loop = false
+	renderBuffer := buffer.FillBuffer(make([]float64, buffSize), seq)
+	seq.Loop = loop
+
+	return renderBuffer, nil
+}
+
+// getSwingOffset returns the delay of the step in samples.
+func (seq Sequencer) getSwingOffset(stepLength float64) float64 {
+
+	if seq.stepCount%2 == 0 {
+		return 0
+	}
+
+	swing := seq.Swing
+	if swing < 0.5 {
+		swing = 0.5
+	} else if swing > 0.75 {
+		swing = 0.75
+	}
+
+	return (swing - 0.5) * 2 * stepLength
+}
+
+// getStepLength returns the length of the steps of the current pattern in
+// samples.
+func (seq Sequencer) getStepLength() float64 {
+	length, err := buffer.CalcDivisionSampleLength(seq.GetSampleRate(), seq.bpm, seq.patterns[seq.patternIdx].Division)
+	if err != nil {
+		return 0
+	}
+	return length
+}
+
+// advance moves to the next step in the chain.
+func (seq *Sequencer) advance() {
+
+	seq.stepIdx++
+	seq.stepCount++
+	if seq.stepIdx < seq.patterns[seq.patternIdx].GetStepCount() {
+		return
+	}
+
+	seq.stepIdx = 0
+	seq.stepCount = 0
+	seq.repeatIdx++
+	if seq.repeatIdx < seq.patterns[seq.patternIdx].getRepeats() {
+		return
+	}
+
+	seq.repeatIdx = 0
+	seq.patternIdx++
+	if seq.patternIdx < len(seq.patterns) {
+		return
+	}
+
+	seq.patternIdx = 0
+	if !seq.Loop {
+		seq.finished = true
+	}
+}
+
+// applyLocks sets the locked parameters and restores the ones that were
+// locked before. Rests and skipped steps have no locks.
+func (seq *Sequencer) applyLocks(locks map[string]float64) {
+	for name, param := range seq.parameters {
+		if value, ok := locks[name]; ok {
+			param.set(value)
+			param.locked = true
+		} else if param.locked {
+			param.set(param.defaultValue)
+			param.locked = false
+		}
+	}
+}
+
+// releaseNote triggers the pending note-off of the note if there is any.
+func (seq *Sequencer) releaseNote(note uint8) {
+	for offIdx := 0; offIdx < len(seq.noteOffs); offIdx++ {
+		if seq.noteOffs[offIdx].note == note {
+			seq.voices.NoteOffMIDI(note)
+			seq.noteOffs = append(seq.noteOffs[:offIdx], seq.noteOffs[offIdx+1:]...)
+			offIdx--
+		}
+	}
+}
+
+// playStep triggers the current step if it plays.
+func (seq *Sequencer) playStep(triggerPos, stepLength float64) {
+
+	step := seq.patterns[seq.patternIdx].GetStep(seq.stepIdx)
+	if step == nil || !step.Active ||
+		(step.Probability < 1 && seq.rndFunc.Float64() >= step.Probability) {
+		seq.applyLocks(nil)
+		return
+	}
+
+	seq.applyLocks(step.Locks)
+
+	// the voice manager releases every voice playing the note
+	seq.releaseNote(step.Note)
+
+	if step.Gate <= 0 {
+		return
+	}
+
+	seq.voices.NoteOnMIDI(step.Note, step.Velocity)
+	seq.noteOffs = append(seq.noteOffs, noteOff{
+		note:   step.Note,
+		sample: triggerPos + step.Gate*stepLength,
+	})
+}
+
+// GetNextSample triggers the steps and the note-offs on their samples and
+// returns the next sample of the voices.
+func (seq *Sequencer) GetNextSample() float64 {
+
+	for offIdx := 0; offIdx < len(seq.noteOffs); offIdx++ {
+		if float64(seq.currSample) >= seq.noteOffs[offIdx].sample {
+			seq.voices.NoteOffMIDI(seq.noteOffs[offIdx].note)
+			seq.noteOffs = append(seq.noteOffs[:offIdx], seq.noteOffs[offIdx+1:]...)
+			offIdx--
+		}
+	}
+
+	for !seq.finished && len(seq.patterns) > 0 {
+
+		stepLength := seq.getStepLength()
+		triggerPos := seq.gridPos + seq.getSwingOffset(stepLength)
+		if float64(seq.currSample) < triggerPos {
+			break
+		}
+
+		seq.playStep(triggerPos, stepLength)
+
+		seq.gridPos += stepLength
+		seq.advance()
+
+		if stepLength <= 0 {
+			break
+		}
+	}
+
+	seq.currSample++
+
+	return seq.voices.GetNextSample()
+}
+
+// Reset releases the playing notes, restores the locked parameters, starts
+// the chain over and resets the voices.
+func (seq *Sequencer) Reset() {
+
+	for _, off := range seq.noteOffs {
+		seq.voices.NoteOffMIDI(off.note)
+	}
+	seq.noteOffs = seq.noteOffs[:0]
+	seq.voices.Reset()
+
+	for _, param := range seq.parameters {
+		if param.locked {
+			param.set(param.defaultValue)
+			param.locked = false
+		}
+	}
+
+	seq.patternIdx = 0
+	seq.repeatIdx = 0
+	seq.stepIdx = 0
+	seq.stepCount = 0
+	seq.currSample = 0
+	seq.gridPos = 0
+	seq.finished = false
+
+	seq.SetSeed(seq.rndSeed)
+}
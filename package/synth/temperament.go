@@ -0,0 +1,95 @@
+package synth
+
+import (
+	"fmt"
+	"math"
+)
+
+type Temperament int
+
+const (
+	EqualTemperament     Temperament = iota
+	Pythagorean                      // pure fifths, the wolf is between G# and Eb
+	QuarterCommaMeantone             // pure major thirds, the wolf is between G# and Eb
+	WerckmeisterIII                  // well temperament, 4 fifths narrowed by 1/4 comma
+	Vallotti                         // well temperament, 6 fifths narrowed by 1/6 comma
+	KirnbergerIII                    // well temperament with a pure major third on the tonic
+	JustIntonation                   // 5-limit just intonation
+)
+
+// temperamentCents holds the pitches of the temperaments in cents from the
+// tonic, for the 12 notes of the octave.
+var temperamentCents = [][12]float64{
+	EqualTemperament:     {0, 100, 200, 300, 400, 500, 600, 700, 800, 900, 1000, 1100},
+	Pythagorean:          {0, 113.685, 203.910, 294.135, 407.820, 498.045, 611.730, 701.955, 815.640, 905.865, 996.090, 1109.775},
+	QuarterCommaMeantone: {0, 76.049, 193.157, 310.265, 386.314, 503.422, 579.471, 696.578, 772.627, 889.735, 1006.843, 1082.892},
+	WerckmeisterIII:      {0, 90.225, 192.180, 294.135, 390.225, 498.045, 588.270, 696.090, 792.180, 888.270, 996.090, 1092.180},
+	Vallotti:             {0, 94.135, 196.090, 298.045, 392.180, 501.955, 592.180, 698.045, 796.090, 894.135, 1000.000, 1090.225},
+	KirnbergerIII:        {0, 90.225, 193.157, 294.135, 386.314, 498.045, 590.224, 696.578, 792.180, 889.735, 996.090, 1088.269},
+	JustIntonation: {
+		0,
+		1200 * math.Log2(16.0/15),
+		1200 * math.Log2(9.0/8),
+		1200 * math.Log2(6.0/5),
+		1200 * math.Log2(5.0/4),
+		1200 * math.Log2(4.0/3),
+		1200 * math.Log2(45.0/32),
+		1200 * math.Log2(3.0/2),
+		1200 * math.Log2(8.0/5),
+		1200 * math.Log2(5.0/3),
+		1200 * math.Log2(9.0/5),
+		1200 * math.Log2(15.0/8),
+	},
+}
+
+// NewTemperamentTuning creates a 12 note tuning with a historical
+// temperament or just intonation. The tonic is the note the temperament is
+// built on, e.g. NoteC or NoteD, so the scales can be re-rooted. The A note
+// of the 4th octave is tuned to freqOfA4 in Hz, the other notes follow the
+// temperament from there.
+func NewTemperamentTuning(temperament Temperament, tonic uint8, freqOfA4 float64) (*Tuning, error) {
+
+	if temperament < 0 || int(temperament) >= len(temperamentCents) {
+		return nil, fmt.Errorf("invalid temperament: %d", temperament)
+	}
+	if tonic >= 12 {
+		return nil, fmt.Errorf("invalid tonic: %d", tonic)
+	}
+
+	cents := temperamentCents[temperament]
+
+	scale := &ScalaScale{
+		Description: fmt.Sprintf("%s on %s", temperament, noteNames[tonic]),
+		Degrees:     make([]float64, 12),
+	}
+	copy(scale.Degrees, cents[1:])
+	scale.Degrees[11] = 1200
+
+	kbm := NewKeyboardMapping()
+	kbm.MiddleNote = 60 + tonic
+	kbm.ReferenceNote = midiNoteA4
+	kbm.ReferenceFreq = freqOfA4
+
+	return NewScalaTuning(scale, kbm)
+}
+
+// String returns the name of the temperament.
+func (temperament Temperament) String() string {
+	switch temperament {
+	case EqualTemperament:
+		return "Equal temperament"
+	case Pythagorean:
+		return "Pythagorean"
+	case QuarterCommaMeantone:
+		return "Quarter-comma meantone"
+	case WerckmeisterIII:
+		return "Werckmeister III"
+	case Vallotti:
+		return "Vallotti"
+	case KirnbergerIII:
+		return "Kirnberger III"
+	case JustIntonation:
+		return "5-limit just intonation"
+	}
+	return fmt.Sprintf("Temperament(%d)", int(temperament))
+}
@@ -0,0 +1,172 @@
+package synth
+
+import (
+	"fmt"
+	"math"
+)
+
+// MIDINoteCount is the number of notes a Tuning covers, MIDI note 0 is C-1
+// and 127 is G9.
+const MIDINoteCount = 128
+
+// midiNoteA4 is the MIDI note number of A4.
+const midiNoteA4 = 69
+
+type Tuning struct {
+	Description string
+	freqTable   [MIDINoteCount]float64
+}
+
+// NewEqualTuning creates a 12 note equal temperament tuning based on an
+// arbitrary frequency in Hz used as the A note of the 4th octave.
+func NewEqualTuning(freqOfA4 float64) *Tuning {
+	tuning, _ := NewEDOTuning(12, midiNoteA4, freqOfA4)
+	return tuning
+}
+
+// NewEDOTuning creates an equal division of the octave tuning, where every
+// MIDI note is one of the divisions higher than the previous one. The
+// reference MIDI note is tuned to the reference frequency in Hz.
+func NewEDOTuning(divisions int, refNote uint8, refFreq float64) (*Tuning, error) {
+
+	if divisions < 1 {
+		return nil, fmt.Errorf("invalid number of divisions: %d", divisions)
+	}
+
+	scale := &ScalaScale{
+		Description: fmt.Sprintf("%d equal divisions of the octave", divisions),
+		Degrees:     make([]float64, divisions),
+	}
+	for degreeIdx := range scale.Degrees {
+		scale.Degrees[degreeIdx] = 1200 * float64(degreeIdx+1) / float64(divisions)
+	}
+
+	kbm := NewKeyboardMapping()
+	kbm.MiddleNote = refNote
+	kbm.ReferenceNote = refNote
+	kbm.ReferenceFreq = refFreq
+
+	return NewScalaTuning(scale, kbm)
+}
+
+// NewScalaTuning creates a tuning from a Scala scale and keyboard mapping.
+// If the mapping is nil, the default one is used, see NewKeyboardMapping.
+// The unmapped notes have 0 frequency.
+func NewScalaTuning(scale *ScalaScale, kbm *KeyboardMapping) (*Tuning, error) {
+
+	if scale == nil || len(scale.Degrees) == 0 {
+		return nil, fmt.Errorf("empty scale")
+	}
+	if kbm == nil {
+		kbm = NewKeyboardMapping()
+	}
+	if kbm.ReferenceFreq <= 0 {
+		return nil, fmt.Errorf("invalid reference frequency: %f", kbm.ReferenceFreq)
+	}
+
+	refCents, ok := getMappedCents(scale, kbm, kbm.ReferenceNote)
+	if !ok {
+		return nil, fmt.Errorf("reference note %d is not mapped", kbm.ReferenceNote)
+	}
+
+	tuningTmp := &Tuning{
+		Description: scale.Description,
+	}
+
+	for midiNote := int(kbm.FirstNote); midiNote <= int(kbm.LastNote); midiNote++ {
+		cents, ok := getMappedCents(scale, kbm, uint8(midiNote))
+		if !ok {
+			continue
+		}
+		tuningTmp.freqTable[midiNote] = kbm.ReferenceFreq * math.Pow(2, (cents-refCents)/1200)
+	}
+
+	return tuningTmp, nil
+}
+
+// LoadScalaTuning creates a tuning from a Scala .scl file and an optional
+// .kbm file. Use an empty kbmFileName for the default mapping.
+func LoadScalaTuning(sclFileName, kbmFileName string) (*Tuning, error) {
+
+	scale, err := ReadScalaScale(sclFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var kbm *KeyboardMapping
+	if kbmFileName != "" {
+		if kbm, err = ReadKeyboardMapping(kbmFileName); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewScalaTuning(scale, kbm)
+}
+
+// floorDivMod returns the floored quotient and the non-negative remainder.
+func floorDivMod(value, divisor int) (int, int) {
+	quotient := value / divisor
+	remainder := value % divisor
+	if remainder < 0 {
+		quotient--
+		remainder += divisor
+	}
+	return quotient, remainder
+}
+
+// getMappedCents returns the pitch of the MIDI note in cents from the first
+// note of the scale on the middle note, or false if it's not mapped.
+func getMappedCents(scale *ScalaScale, kbm *KeyboardMapping, midiNote uint8) (float64, bool) {
+
+	degree := int(midiNote) - int(kbm.MiddleNote)
+
+	if len(kbm.Mapping) > 0 {
+
+		octaveDegree := kbm.OctaveDegree
+		if octaveDegree == 0 {
+			octaveDegree = len(scale.Degrees)
+		}
+
+		mapOctave, mapIdx := floorDivMod(degree, len(kbm.Mapping))
+		if kbm.Mapping[mapIdx] < 0 {
+			return 0, false
+		}
+		degree = kbm.Mapping[mapIdx] + mapOctave*octaveDegree
+	}
+
+	period := scale.Degrees[len(scale.Degrees)-1]
+
+	octave, degreeIdx := floorDivMod(degree, len(scale.Degrees))
+	cents := float64(octave) * period
+	if degreeIdx > 0 {
+		cents += scale.Degrees[degreeIdx-1]
+	}
+
+	return cents, true
+}
+
+// GetFrequency returns the frequency of a MIDI note in Hz, or 0 if the note
+// is not mapped.
+func (tuning *Tuning) GetFrequency(midiNote uint8) float64 {
+	if int(midiNote) >= MIDINoteCount {
+		return 0
+	}
+	return tuning.freqTable[midiNote]
+}
+
+// GetFreqOf returns the frequency of a note in Hz, or 0 if the note is not
+// mapped. Octave 0 starts at MIDI note 12.
+func (tuning *Tuning) GetFreqOf(octave uint8, note uint8) float64 {
+	return tuning.GetFrequency(GetMIDINote(octave, note))
+}
+
+// GetMIDINote returns the MIDI note number of a note. Octave 0 starts at
+// MIDI note 12, so the notes below can't be addressed this way. The notes
+// above the MIDI range return a number above 127.
+func GetMIDINote(octave uint8, note uint8) uint8 {
+	midiNote := (int(octave)+1)*12 + int(note)
+	if midiNote > math.MaxUint8 {
+		return math.MaxUint8
+	}
+	return uint8(midiNote)
+}
@@ -0,0 +1,115 @@
+package synth
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/rawbits2010/LibBitDauer/package/synth/generator"
+)
+
+type DetuneCurve uint
+
+const (
+	DetuneLinear      DetuneCurve = iota // evenly spaced copies
+	DetuneExponential                    // copies gather around the center
+)
+
+// SetUnison sets the number of detuned copies of the wave generator that
+// play together, the classic "supersaw" sound. The copies are made here, and
+// they take the settings of the wave generator on every restart, so they
+// follow its changes. Use 1 to turn it off. The copies don't follow the sync
+// master.
+func (osc *Oscillator) SetUnison(voices int) {
+	if voices < 1 {
+		voices = 1
+	}
+	osc.unisonVoices = voices
+	osc.buildUnison()
+}
+
+// GetUnison returns the number of unison copies.
+func (osc Oscillator) GetUnison() int {
+	return osc.unisonVoices
+}
+
+// buildUnison creates the copies of the wave generator, and restarts them.
+func (osc *Oscillator) buildUnison() {
+
+	if osc.unisonVoices <= 1 {
+		osc.unisonWaves = nil
+		return
+	}
+
+	osc.unisonRnd = rand.New(rand.NewSource(osc.UnisonSeed))
+
+	osc.unisonWaves = make([]*generator.FunctionGenerator, osc.unisonVoices)
+	for voiceIdx := range osc.unisonWaves {
+		osc.unisonWaves[voiceIdx] = generator.NewFunctionGenerator(osc.sampleRate)
+	}
+
+	osc.resetUnison()
+}
+
+// resetUnison copies the settings of the wave generator into the copies,
+// and restarts them with fixed or random start phases. It doesn't allocate,
+// so it can run on every NoteOn.
+func (osc *Oscillator) resetUnison() {
+
+	if len(osc.unisonWaves) == 0 {
+		return
+	}
+
+	osc.unisonRnd.Seed(osc.UnisonSeed)
+
+	for _, wave := range osc.unisonWaves {
+
+		*wave = *osc.Wave
+		wave.SetSyncSource(nil)
+		wave.Reset()
+		if osc.UnisonRandomPhase {
+			wave.ShiftPhase(osc.unisonRnd.Float64() * 360)
+		}
+	}
+}
+
+// getUnisonPosition returns the [-1-1] position of the copy in the stack.
+func (osc Oscillator) getUnisonPosition(voiceIdx int) float64 {
+	return 2*float64(voiceIdx)/float64(len(osc.unisonWaves)-1) - 1
+}
+
+// getUnisonSample returns the mono, left and right samples of the copies
+// for the frequency in Hz, phase offset in radians and pan position.
+func (osc *Oscillator) getUnisonSample(frequency, phaseOffset, pan float64) (float64, float64, float64) {
+
+	var sample, left, right float64
+	for voiceIdx, wave := range osc.unisonWaves {
+
+		pos := osc.getUnisonPosition(voiceIdx)
+
+		detune := pos * osc.UnisonDetune
+		if osc.UnisonCurve == DetuneExponential {
+			detune = pos * math.Abs(pos) * osc.UnisonDetune
+		}
+
+		wave.Frequency = frequency * math.Pow(2, detune/1200)
+		wave.PhaseOffset = phaseOffset
+		voiceSample := wave.GetNextSample()
+
+		panLeft, panRight := getPanGains(pan + pos*osc.UnisonSpread)
+
+		sample += voiceSample
+		left += voiceSample * panLeft
+		right += voiceSample * panRight
+	}
+
+	norm := 1 / math.Sqrt(float64(len(osc.unisonWaves)))
+
+	return sample * norm, left * norm, right * norm
+}
+
+// getPanGains returns the left and right gains for the pan position
+// [-1-1], keeping unity gain in the center.
+func getPanGains(pan float64) (float64, float64) {
+	pan = math.Min(math.Max(pan, -1), 1)
+	return math.Min(1, 1-pan), math.Min(1, 1+pan)
+}
@@ -0,0 +1,234 @@
+package synth
+
+import "github.com/rawbits2010/LibBitDauer/package/synth/generator"
+
+// Voice is an interface for sound sources that can be played by the
+// VoiceManager with note-on and note-off events.
+type Voice interface {
+	generator.Generator
+	NoteOn(frequency, velocity float64)
+	NoteOff()
+	IsActive() bool    // still producing sound, e.g. in the release phase
+	GetLevel() float64 // current loudness, used for voice stealing
+}
+
+type VoiceStealMode int
+
+const (
+	StealOldest   VoiceStealMode = iota // the earliest started note
+	StealQuietest                       // the note with the lowest level
+	StealSameNote                       // the same note if playing, or the oldest
+)
+
+type voiceSlot struct {
+	voice Voice
+	key   uint8
+	gate  bool   // the note is held
+	idle  bool   // free to use without stealing
+	age   uint64 // note-on order
+}
+
+type VoiceManager struct {
+	StealMode VoiceStealMode
+	Tuning    *Tuning // the note frequencies, nil uses the default tuning
+
+	voices      []voiceSlot
+	noteCounter uint64
+}
+
+// NewVoiceManager creates a polyphonic voice manager that implements the
+// Generator interface. It calls newVoice polyphony times to create the
+// voices, and mixes the sound of the playing ones. When all the voices
+// are used, a new note steals one based on the StealMode.
+func NewVoiceManager(polyphony int, newVoice func() Voice) *VoiceManager {
+
+	if polyphony < 1 {
+		polyphony = 1
+	}
+
+	vmTmp := &VoiceManager{
+		StealMode: StealOldest,
+		voices:    make([]voiceSlot, polyphony),
+	}
+
+	for voiceIdx := range vmTmp.voices {
+		vmTmp.voices[voiceIdx].voice = newVoice()
+		vmTmp.voices[voiceIdx].idle = true
+	}
+
+	return vmTmp
+}
+
+// NewOscillatorVoiceManager creates a polyphonic voice manager with
+// oscillator voices cloned from the template. See Oscillator.Clone for
+// the details.
+func NewOscillatorVoiceManager(template *Oscillator, polyphony int) *VoiceManager {
+	return NewVoiceManager(polyphony, func() Voice {
+		return template.Clone()
+	})
+}
+
+// GetSampleRate returns the sample rate of the voices.
+func (vm VoiceManager) GetSampleRate() uint {
+	return vm.voices[0].voice.GetSampleRate()
+}
+
+// GetPolyphony returns the number of voices.
+func (vm VoiceManager) GetPolyphony() int {
+	return len(vm.voices)
+}
+
+// GetActiveVoiceCount returns the number of voices currently producing sound.
+func (vm VoiceManager) GetActiveVoiceCount() int {
+	count := 0
+	for _, slot := range vm.voices {
+		if !slot.idle {
+			count++
+		}
+	}
+	return count
+}
+
+// getTuning returns the tuning of the voice manager, or the default one.
+func (vm VoiceManager) getTuning() *Tuning {
+	if vm.Tuning == nil {
+		return defaultTuning
+	}
+	return vm.Tuning
+}
+
+// NoteOn starts playing a note with a velocity [0-1]. The frequency comes
+// from the Tuning, see GetFreqOf.
+func (vm *VoiceManager) NoteOn(octave uint8, note uint8, velocity float64) {
+	vm.NoteOnMIDI(GetMIDINote(octave, note), velocity)
+}
+
+// NoteOnMIDI starts playing a MIDI note with a velocity [0-1]. The
+// frequency comes from the Tuning, the unmapped notes are ignored.
+func (vm *VoiceManager) NoteOnMIDI(midiNote uint8, velocity float64) {
+
+	frequency := vm.getTuning().GetFrequency(midiNote)
+	if frequency <= 0 {
+		return
+	}
+
+	slot := &vm.voices[vm.selectVoice(midiNote)]
+
+	slot.key = midiNote
+	slot.gate = true
+	slot.idle = false
+	slot.age = vm.noteCounter
+	vm.noteCounter++
+
+	slot.voice.NoteOn(frequency, velocity)
+}
+
+// NoteOff releases all the voices playing the note.
+func (vm *VoiceManager) NoteOff(octave uint8, note uint8) {
+	vm.NoteOffMIDI(GetMIDINote(octave, note))
+}
+
+// NoteOffMIDI releases all the voices playing the MIDI note.
+func (vm *VoiceManager) NoteOffMIDI(key uint8) {
+	for voiceIdx := range vm.voices {
+		slot := &vm.voices[voiceIdx]
+		if slot.gate && slot.key == key {
+			slot.gate = false
+			slot.voice.NoteOff()
+		}
+	}
+}
+
+// AllNotesOff releases all the held notes.
+func (vm *VoiceManager) AllNotesOff() {
+	for voiceIdx := range vm.voices {
+		slot := &vm.voices[voiceIdx]
+		if slot.gate {
+			slot.gate = false
+			slot.voice.NoteOff()
+		}
+	}
+}
+
+// selectVoice returns the index of the voice to use for a new note.
+func (vm VoiceManager) selectVoice(key uint8) int {
+
+	if vm.StealMode == StealSameNote {
+		for voiceIdx, slot := range vm.voices {
+			if !slot.idle && slot.key == key {
+				return voiceIdx
+			}
+		}
+	}
+
+	for voiceIdx, slot := range vm.voices {
+		if slot.idle {
+			return voiceIdx
+		}
+	}
+
+	// released voices are stolen first
+	candidates := make([]int, 0, len(vm.voices))
+	for voiceIdx, slot := range vm.voices {
+		if !slot.gate {
+			candidates = append(candidates, voiceIdx)
+		}
+	}
+	if len(candidates) == 0 {
+		for voiceIdx := range vm.voices {
+			candidates = append(candidates, voiceIdx)
+		}
+	}
+
+	selected := candidates[0]
+	for _, voiceIdx := range candidates[1:] {
+		switch vm.StealMode {
+
+		case StealQuietest:
+			if vm.voices[voiceIdx].voice.GetLevel() < vm.voices[selected].voice.GetLevel() {
+				selected = voiceIdx
+			}
+
+		default:
+			if vm.voices[voiceIdx].age < vm.voices[selected].age {
+				selected = voiceIdx
+			}
+		}
+	}
+
+	return selected
+}
+
+// GetNextSample returns the sum of the next samples of the playing voices.
+// The result is not normalized, set the voice volumes accordingly.
+func (vm *VoiceManager) GetNextSample() float64 {
+
+	var sample float64
+	for voiceIdx := range vm.voices {
+		slot := &vm.voices[voiceIdx]
+
+		if slot.idle {
+			continue
+		}
+		if !slot.gate && !slot.voice.IsActive() {
+			slot.idle = true
+			continue
+		}
+
+		sample += slot.voice.GetNextSample()
+	}
+
+	return sample
+}
+
+// Reset restarts the held notes from the beginning and silences the
+// released ones.
+func (vm *VoiceManager) Reset() {
+	for voiceIdx := range vm.voices {
+		slot := &vm.voices[voiceIdx]
+		if !slot.gate {
+			slot.idle = true
+		}
+		slot.voice.Reset()
+	}
+}